@@ -2,35 +2,81 @@ package gql
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"template/core"
+	"template/i18n"
+	builtinerrors "template/internal/builtinErrors"
+	"template/service"
+	"template/service/auth"
 
 	"github.com/gin-gonic/gin"
-	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/graphql-go/handler"
 )
 
-var graphQLHandler *handler.Handler
+// GraphQL GraphQL 接口, 解析器和令牌校验使用创建时传入的服务
+type GraphQL struct {
+	tokens *auth.AuthService
+	config handler.Config
+}
 
-func init() {
-	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query:    rootQuery, // Query
-		Mutation: rootMutation, // Mutation
-	})
+// New 创建使用 services 的 GraphQL 接口
+func New(services *service.Service) (GraphQL, error) {
+	schema, err := newSchema(services)
 	if err != nil {
-		panic("\nfailed to create schema: " + err.Error())
+		return GraphQL{}, fmt.Errorf("gql: create schema: %w", err)
 	}
-	graphQLHandler = handler.New(&handler.Config{
-		Schema: &schema,
-		Pretty: true,
-		Playground: false, // this is debug playground
-		RootObjectFn: func(ctx context.Context, r *http.Request) map[string]interface{} {
-			return map[string]interface{}{
-				"headers":r.Header,
-			}
+	return GraphQL{
+		tokens: services.Auth,
+		config: handler.Config{
+			Schema: &schema,
+			Pretty: true,
+			Playground: false, // this is debug playground
+			RootObjectFn: func(ctx context.Context, r *http.Request) map[string]interface{} {
+				return map[string]interface{}{
+					"headers":r.Header,
+				}
+			},
 		},
-	})
+	}, nil
+}
+
+// formatError 使用本次请求的语言翻译业务错误, 并在 extensions 中返回错误码
+func formatError(locale string) func(err error) gqlerrors.FormattedError {
+	return func(err error) gqlerrors.FormattedError {
+		formatted := gqlerrors.FormatError(err)
+		// 解析器返回的错误保存在 gqlerrors.Error 的 OriginalError 中
+		original := err
+		if located, ok := err.(*gqlerrors.Error); ok && located.OriginalError != nil {
+			original = located.OriginalError
+		}
+		var exception *builtinerrors.Exception
+		if errors.As(original, &exception) {
+			if exception.Locale() == "" {
+				exception = exception.WithLocale(locale)
+			}
+			formatted.Message = exception.Error()
+			if formatted.Extensions == nil {
+				formatted.Extensions = make(map[string]interface{})
+			}
+			formatted.Extensions["code"] = exception.Code()
+		}
+		return formatted
+	}
 }
 
-func GraphQLAPI(ctx *gin.Context) {
-	graphQLHandler.ServeHTTP(ctx.Writer, ctx.Request)
+func (graphQL GraphQL) GraphQLAPI(ctx *gin.Context) {
+	// GraphQL 路由是公开的, 携带有效访问令牌时将用户信息交给字段 guard 判断权限
+	if token := core.GetTokenFromRequest(ctx); token != "" {
+		claims, err := graphQL.tokens.Authenticate(token)
+		if err == nil {
+			ctx.Request = ctx.Request.WithContext(auth.WithClaims(ctx.Request.Context(), claims))
+		}
+	}
+	// 错误信息的语言按请求协商, 每个请求使用单独的 handler
+	config := graphQL.config
+	config.FormatErrorFn = formatError(i18n.LocaleFromContext(ctx.Request.Context()))
+	handler.New(&config).ServeHTTP(ctx.Writer, ctx.Request)
 }
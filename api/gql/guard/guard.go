@@ -0,0 +1,73 @@
+package guard
+
+import (
+	"template/internal/builtin"
+	"template/service/auth"
+	"template/service/rbac"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Guard 字段级别的访问控制, 包装字段的 Resolve
+type Guard func(resolve graphql.FieldResolveFn) graphql.FieldResolveFn
+
+// Protect 为字段加上访问控制, 多个 guard 按顺序检查
+//
+//	"users": guard.Protect(query.UsersQuery, guard.RequirePermission("user:read"))
+func Protect(field *graphql.Field, guards ...Guard) *graphql.Field {
+	protected := *field
+	resolve := field.Resolve
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	for index := len(guards) - 1; index >= 0; index-- {
+		resolve = guards[index](resolve)
+	}
+	protected.Resolve = resolve
+	return &protected
+}
+
+// Authenticated 要求请求携带有效的访问令牌
+func Authenticated() Guard {
+	return func(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			if _, ok := auth.ClaimsFromContext(p.Context); !ok {
+				return nil, builtin.ErrUnauthorized
+			}
+			return resolve(p)
+		}
+	}
+}
+
+// RequirePermission 要求当前用户拥有全部指定权限
+func RequirePermission(codes ...string) Guard {
+	required := rbac.MustParsePermissions(codes...)
+	return func(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			claims, ok := auth.ClaimsFromContext(p.Context)
+			if !ok {
+				return nil, builtin.ErrUnauthorized
+			}
+			if !claims.Permissions().Has(required) {
+				return nil, builtin.ErrForbidden
+			}
+			return resolve(p)
+		}
+	}
+}
+
+// RequireRole 要求当前用户拥有任意一个指定角色
+func RequireRole(roles ...string) Guard {
+	return func(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+		return func(p graphql.ResolveParams) (interface{}, error) {
+			claims, ok := auth.ClaimsFromContext(p.Context)
+			if !ok {
+				return nil, builtin.ErrUnauthorized
+			}
+			if !rbac.HasAnyRole(claims.Roles(), roles...) {
+				return nil, builtin.ErrForbidden
+			}
+			return resolve(p)
+		}
+	}
+}
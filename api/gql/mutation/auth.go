@@ -9,51 +9,62 @@ import (
 	"github.com/graphql-go/graphql"
 )
 
-
-var LoginMutation = &graphql.Field{
-	Type: types.AuthTokenType,
-	Args: graphql.FieldConfigArgument{
-		"email": &graphql.ArgumentConfig{
-			Type: graphql.String,
+// LoginMutation 使用邮箱和密码登录, 与 REST 登录接口使用相同的校验
+func LoginMutation(services *service.Service) *graphql.Field {
+	return &graphql.Field{
+		Type: types.AuthTokenType,
+		Args: graphql.FieldConfigArgument{
+			"email": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"password": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
 		},
-		"password": &graphql.ArgumentConfig{
-			Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			userLoginInput := &dto.UserLoginDTO{
+				Email:    p.Args["email"].(string),
+				Password: p.Args["password"].(string),
+			}
+			err := core.ValidateParams(p.Context, userLoginInput)
+			if err != nil {
+				return nil, err
+			}
+			// 账号不存在与密码错误返回相同的错误
+			user, err := services.Account.Authenticate(userLoginInput.Email, userLoginInput.Password)
+			if err != nil {
+				return nil, err
+			}
+			accessToken, refreshToken, err := services.Auth.Token(user.Email, user.GID, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{
+				"access_token":  accessToken,
+				"refresh_token": refreshToken,
+			}, nil
 		},
-	},
-	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		userLoginInput := &dto.UserLoginDTO{
-			Email:    p.Args["email"].(string),
-			Password: p.Args["password"].(string),
-		}
-		err := core.ValidateParams(userLoginInput)
-		if err != nil {
-			return nil, err
-		}
-		user,err := service.ServiceBoot.User.GetByGID("")
-		if err != nil {
-			return nil,err
-		}
-		access_token,refresh,err := service.ServiceBoot.Auth.Token(
-			user.Name,
-			user.GID,
-			user.ID,
-		)
-		if err != nil {
-			return nil,err
-		}
-		return map[string]any{
-			"access_token":  access_token,
-			"refresh_token": refresh,
-		}, nil
-	},
-}
-var RefreshMutation = &graphql.Field{
-	Type: types.AuthTokenType,
-	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		refresh := ""
-		return map[string]any{
-			"refresh_token": refresh,
-		}, nil
-	},
+	}
 }
 
+// RefreshMutation 轮换刷新令牌, 旧的刷新令牌随即失效
+func RefreshMutation(services *service.Service) *graphql.Field {
+	return &graphql.Field{
+		Type: types.AuthTokenType,
+		Args: graphql.FieldConfigArgument{
+			"refresh_token": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			accessToken, refreshToken, err := services.Auth.Rotate(p.Args["refresh_token"].(string))
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{
+				"access_token":  accessToken,
+				"refresh_token": refreshToken,
+			}, nil
+		},
+	}
+}
@@ -1 +1,37 @@
 package query
+
+import (
+	"template/api/gql/types"
+	"template/core"
+	"template/dto"
+	"template/internal/builtin"
+	"template/service/user"
+
+	"github.com/graphql-go/graphql"
+)
+
+// UsersQuery 使用 userService 查询的用户列表, 参数与 REST 的 /api/v1/user/list 相同
+func UsersQuery(userService *user.UserService) *graphql.Field {
+	return &graphql.Field{
+		Type: types.UserListType,
+		Args: types.ListArgs(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			q, err := dto.UserQuery.ParseArgs(p.Args)
+			if err != nil {
+				return nil, builtin.ErrInvalidParams
+			}
+			if q.Keyset {
+				users, cursors, err := userService.Seek(q)
+				if err != nil {
+					return nil, err
+				}
+				return core.NewPage(users, core.WithCursors(q.Size, cursors)), nil
+			}
+			users, total, err := userService.List(q)
+			if err != nil {
+				return nil, err
+			}
+			return core.NewPage(users, core.WithOffset(total, q.Page, q.Size)), nil
+		},
+	}
+}
@@ -1,22 +1,37 @@
 package gql
 
 import (
+	"template/api/gql/guard"
 	"template/api/gql/mutation"
+	"template/api/gql/query"
+	"template/service"
 
 	"github.com/graphql-go/graphql"
 )
 
-var rootQuery = graphql.NewObject(
-	graphql.ObjectConfig{
-		Fields: graphql.Fields{},
-	},
-)
+// newSchema 创建字段使用 services 的 schema
+func newSchema(services *service.Service) (graphql.Schema, error) {
+	rootQuery := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"users": guard.Protect(query.UsersQuery(services.User), guard.RequirePermission("user:read")),
+			},
+		},
+	)
 
-var rootMutation = graphql.NewObject(
-	graphql.ObjectConfig{
-		Fields: graphql.Fields{
-			"login":mutation.LoginMutation,
-			"refresh":mutation.RefreshMutation,
+	rootMutation := graphql.NewObject(
+		graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"login":mutation.LoginMutation(services),
+				"refresh":mutation.RefreshMutation(services),
+			},
 		},
-	},
-)
+	)
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    rootQuery, // Query
+		Mutation: rootMutation, // Mutation
+	})
+}
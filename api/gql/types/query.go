@@ -0,0 +1,26 @@
+package types
+
+import "github.com/graphql-go/graphql"
+
+// FilterInputType 过滤条件, 与 REST 的 filter[field][op]=value 相同
+var FilterInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "FilterInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"op":     &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "eq ne lt gt in like between isnull, default eq"},
+		"value":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"values": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String), Description: "values of in and between"},
+	},
+})
+
+// ListArgs 列表查询的过滤、排序和分页参数, 使用 query.Schema.ParseArgs 解析
+func ListArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"filter": &graphql.ArgumentConfig{Type: graphql.NewList(FilterInputType), Description: "conditions joined with AND"},
+		"or":     &graphql.ArgumentConfig{Type: graphql.NewList(FilterInputType), Description: "conditions joined with OR"},
+		"sort":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String), Description: "prefix - for descending"},
+		"page":   &graphql.ArgumentConfig{Type: graphql.Int},
+		"size":   &graphql.ArgumentConfig{Type: graphql.Int},
+		"cursor": &graphql.ArgumentConfig{Type: graphql.String, Description: "empty for the first page of cursor pagination"},
+	}
+}
@@ -5,5 +5,29 @@ import "github.com/graphql-go/graphql"
 var UserType = graphql.NewObject(graphql.ObjectConfig{
 	Name: "user",
 	Fields: graphql.Fields{
+		"gid":         &graphql.Field{Type: graphql.String},
+		"name":        &graphql.Field{Type: graphql.String},
+		"email":       &graphql.Field{Type: graphql.String},
+		"description": &graphql.Field{Type: graphql.String},
+		"phone":       &graphql.Field{Type: graphql.String},
+		"country":     &graphql.Field{Type: graphql.String},
+		"gender":      &graphql.Field{Type: graphql.String},
+		"avatar":      &graphql.Field{Type: graphql.String},
+		"follows":     &graphql.Field{Type: graphql.Int},
+		"following":   &graphql.Field{Type: graphql.Int},
+		"version":     &graphql.Field{Type: graphql.Int},
+	},
+})
+
+var UserListType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserList",
+	Fields: graphql.Fields{
+		"items":    &graphql.Field{Type: graphql.NewList(UserType)},
+		"total":    &graphql.Field{Type: graphql.Int},
+		"page":     &graphql.Field{Type: graphql.Int},
+		"size":     &graphql.Field{Type: graphql.Int},
+		"has_more": &graphql.Field{Type: graphql.Boolean},
+		"next":     &graphql.Field{Type: graphql.String},
+		"prev":     &graphql.Field{Type: graphql.String},
 	},
 })
@@ -2,12 +2,22 @@ package api
 
 import (
 	"template/api/v1/auth"
+	"template/api/v1/role"
 	"template/api/v1/user"
+	"template/service"
 )
 
 type API struct {
 	Auth auth.AuthController
 	User user.UserController
+	Role role.RoleController
 }
 
-var AppAPI = new(API)
+// New 创建使用 services 的控制器
+func New(services *service.Service) *API {
+	return &API{
+		Auth: auth.New(services),
+		User: user.New(services),
+		Role: role.New(services),
+	}
+}
@@ -1,20 +1,25 @@
 package auth
 
 import (
+	"net/http"
 	"template/core"
 	"template/dto"
 	"template/internal/builtin"
+	"template/middleware"
 	"template/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 
-type AuthController struct{}
-
+type AuthController struct {
+	services *service.Service
+}
 
-var authService = service.ServiceBoot.Auth
-var accountService = service.ServiceBoot.Account
+// New 使用 services 处理请求
+func New(services *service.Service) AuthController {
+	return AuthController{services: services}
+}
 
 // LoginHandler 处理登录请求
 //
@@ -30,27 +35,21 @@ func (auth AuthController) Login(ctx *gin.Context){
 	core.ResponseError(ctx, builtin.ErrInvalidParams)
 		return
 	}
-	err := core.ValidateParams(param)
+	err := core.ValidateParams(ctx.Request.Context(), param)
 	if err != nil {
 		core.ResponseError(ctx, err)
 		return
 	}
 
-	// 获取用户信息
-    user, err := accountService.GetByEmail(param.Email)
+	// 验证账号密码, 账号不存在与密码错误返回相同的错误
+    user, err := auth.services.Account.Authenticate(param.Email, param.Password)
     if err != nil {
-        core.ResponseError(ctx, builtin.ErrUserNotFound)
-        return
-    }
-
-    // 验证密码
-    if user.Password != param.Password {
-        core.ResponseError(ctx, builtin.ErrInvalidPassword)
+        core.ResponseError(ctx, err)
         return
     }
 
     // 生成令牌
-    accessToken, refreshToken, err := authService.Token(user.Email, user.GID, user.ID)
+    accessToken, refreshToken, err := auth.services.Auth.Token(user.Email, user.GID, user.ID)
     if err != nil {
         core.ResponseError(ctx, err)
         return
@@ -80,48 +79,50 @@ func (auth AuthController) Refresh(ctx *gin.Context){
         return
     }
 
-    // 解析Token
-    claims, err := authService.ParseToken(refreshToken)
-    if err != nil {
-        core.ResponseError(ctx, err)
-        return
-    }
-
-    // 验证是否为刷新令牌
-    if claims.Subject != "refresh" {
-        core.ResponseError(ctx, builtin.ErrUnauthorized)
-        return
-    }
-
-    // 生成新的访问令牌
-    accessToken, err := authService.CreateAccessToken(claims.AccountId, claims.GID, claims.Uid)
+    // 轮换刷新令牌, 旧的刷新令牌随即失效
+    accessToken, newRefreshToken, err := auth.services.Auth.Rotate(refreshToken)
     if err != nil {
         core.ResponseError(ctx, err)
         return
     }
 	ctx.Writer.Header().Set("Authorization", "Bearer "+accessToken)
+	ctx.Writer.Header().Set("Refresh-Token", newRefreshToken)
     core.ResponseData(ctx, gin.H{
-        "access_token": accessToken,
-        "token_type":   "Bearer",
+        "access_token":  accessToken,
+        "refresh_token": newRefreshToken,
+        "token_type":    "Bearer",
     })
 }
 
-func (auth AuthController) AuthorizeClient(ctx *gin.Context){
-
-}
-
-func (auth AuthController) Oauth2Login(ctx *gin.Context){
-	// TODO
-}
-
-func (auth AuthController) Oauth2Callback(ctx *gin.Context){
-	// TODO
-}
-
-func (auth AuthController) Oauth2Logout(ctx *gin.Context){
-
+// Logout 退出登录, 吊销当前访问令牌以及同一次登录签发的刷新令牌
+//
+// @Summary Logout
+// @Description revoke current access token and its refresh tokens
+// @Tags auth
+// @Accept json
+// @Router /api/v1/auth/logout [post]
+func (auth AuthController) Logout(ctx *gin.Context){
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		core.ResponseError(ctx, builtin.ErrUnauthorized)
+		return
+	}
+	if err := auth.services.Auth.Revoke(claims); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, nil)
 }
 
-func (auth AuthController) Oauth2Refresh(ctx *gin.Context){
-
+// JWKS 公开签名公钥, 供其他服务校验令牌
+//
+// @Summary JWKS
+// @Description public keys used to verify issued tokens
+// @Tags auth
+// @Produce json
+// @Router /.well-known/jwks.json [get]
+func (auth AuthController) JWKS(ctx *gin.Context){
+	// 响应体遵循 RFC 7517, 不使用统一的响应包装
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, auth.services.Auth.KeyRing().JWKS())
 }
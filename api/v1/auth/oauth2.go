@@ -0,0 +1,388 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"template/core"
+	"template/dto"
+	"template/internal/builtin"
+	"template/middleware"
+	"template/model"
+	"template/service/oauth"
+	"template/service/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseOauthError OAuth2 端点按 RFC 6749 第5.2节返回错误, 不使用统一的响应包装
+func responseOauthError(ctx *gin.Context, err error) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = oauth.ErrServerError
+	}
+	if errors.Is(oauthErr, oauth.ErrInvalidClient) {
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(oauthErr.Status(), oauthErr)
+}
+
+func responseOauthData(ctx *gin.Context, data any) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, data)
+}
+
+// authenticateClient 支持 client_secret_basic 和 client_secret_post 两种客户端认证方式
+func (auth AuthController) authenticateClient(ctx *gin.Context) (*model.OauthClientModel, bool) {
+	clientID, secret, ok := ctx.Request.BasicAuth()
+	if ok {
+		// RFC 6749 第2.3.1节要求对凭证进行 form 编码
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+	client, err := auth.services.Oauth.AuthenticateClient(clientID, secret)
+	if err != nil {
+		responseOauthError(ctx, err)
+		return nil, false
+	}
+	return client, true
+}
+
+// redirectURL 在回调地址上附加授权结果
+func redirectURL(redirectURI string, values url.Values) string {
+	separator := "?"
+	if strings.Contains(redirectURI, "?") {
+		separator = "&"
+	}
+	return redirectURI + separator + values.Encode()
+}
+
+func bindOauthParams[T any](ctx *gin.Context, param *T) bool {
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return false
+	}
+	if err := core.ValidateParams(ctx.Request.Context(), param); err != nil {
+		core.ResponseError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// AuthorizeClient 校验授权请求, 返回同意页面需要展示的客户端和授权范围
+//
+// @Summary OAuth2 Authorize
+// @Description validate authorization request (RFC 6749 4.1.1, RFC 7636)
+// @Tags oauth2
+// @Param response_type query string true "code"
+// @Param client_id query string true "client id"
+// @Param redirect_uri query string false "redirect uri"
+// @Param scope query string false "scope"
+// @Param state query string false "state"
+// @Param code_challenge query string false "PKCE code challenge"
+// @Param code_challenge_method query string false "plain or S256"
+// @Router /api/v1/oauth2/authorize [get]
+func (auth AuthController) AuthorizeClient(ctx *gin.Context) {
+	request := new(oauth.AuthorizeRequest)
+	if err := ctx.ShouldBindQuery(request); err != nil {
+		responseOauthError(ctx, oauth.ErrInvalidRequest)
+		return
+	}
+	client, err := auth.services.Oauth.ValidateAuthorize(request)
+	if err != nil {
+		responseOauthError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, gin.H{
+		"client": gin.H{
+			"client_id": client.ClientID,
+			"name":      client.Name,
+		},
+		"redirect_uri":          request.RedirectURI,
+		"scope":                 strings.Fields(request.Scope),
+		"state":                 request.State,
+		"code_challenge":        request.CodeChallenge,
+		"code_challenge_method": request.CodeChallengeMethod,
+	})
+}
+
+// Oauth2Consent 用户同意或拒绝授权, 返回携带授权码或错误的回调地址
+//
+// @Summary OAuth2 Consent
+// @Description approve or deny an authorization request
+// @Param request body dto.OauthConsentDTO true "consent"
+// @Tags oauth2
+// @Accept json
+// @Router /api/v1/oauth2/authorize [post]
+func (auth AuthController) Oauth2Consent(ctx *gin.Context) {
+	param := new(dto.OauthConsentDTO)
+	if !bindOauthParams(ctx, param) {
+		return
+	}
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		core.ResponseError(ctx, builtin.ErrUnauthorized)
+		return
+	}
+	request := &oauth.AuthorizeRequest{
+		ResponseType:        param.ResponseType,
+		ClientID:            param.ClientID,
+		RedirectURI:         param.RedirectURI,
+		Scope:               param.Scope,
+		State:               param.State,
+		CodeChallenge:       param.CodeChallenge,
+		CodeChallengeMethod: param.CodeChallengeMethod,
+	}
+	client, err := auth.services.Oauth.ValidateAuthorize(request)
+	if client == nil {
+		// 客户端或回调地址无效时不能重定向
+		responseOauthError(ctx, err)
+		return
+	}
+
+	values := url.Values{}
+	if request.State != "" {
+		values.Set("state", request.State)
+	}
+	var oauthErr *oauth.Error
+	switch {
+	case errors.As(err, &oauthErr):
+		values.Set("error", oauthErr.Code)
+		if oauthErr.Description != "" {
+			values.Set("error_description", oauthErr.Description)
+		}
+	case err != nil:
+		values.Set("error", oauth.ErrServerError.Code)
+	case !param.Approve:
+		values.Set("error", oauth.ErrAccessDenied.Code)
+	default:
+		code, err := auth.services.Oauth.IssueCode(request, claims.GID)
+		if err != nil {
+			values.Set("error", oauth.ErrServerError.Code)
+			break
+		}
+		values.Set("code", code)
+	}
+	core.ResponseData(ctx, gin.H{"redirect_uri": redirectURL(request.RedirectURI, values)})
+}
+
+// Oauth2Token 令牌端点, 支持 authorization_code, client_credentials 和 refresh_token
+//
+// @Summary OAuth2 Token
+// @Description token endpoint (RFC 6749 3.2)
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Param grant_type formData string true "grant type"
+// @Router /api/v1/oauth2/token [post]
+func (auth AuthController) Oauth2Token(ctx *gin.Context) {
+	auth.grant(ctx, ctx.PostForm("grant_type"))
+}
+
+// Oauth2Refresh 使用刷新令牌换取新令牌, 等同于 grant_type=refresh_token 的令牌端点
+//
+// @Summary OAuth2 Refresh
+// @Description refresh token grant (RFC 6749 6)
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Param refresh_token formData string true "refresh token"
+// @Router /api/v1/oauth2/refresh [post]
+func (auth AuthController) Oauth2Refresh(ctx *gin.Context) {
+	auth.grant(ctx, oauth.GrantRefreshToken)
+}
+
+func (auth AuthController) grant(ctx *gin.Context, grantType string) {
+	if grantType == "" {
+		responseOauthError(ctx, oauth.ErrInvalidRequest.WithDescription("grant_type is required"))
+		return
+	}
+	client, ok := auth.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	var (
+		response *oauth.TokenResponse
+		err      error
+	)
+	switch grantType {
+	case oauth.GrantAuthorizationCode:
+		response, err = auth.services.Oauth.ExchangeCode(client, ctx.PostForm("code"), ctx.PostForm("redirect_uri"), ctx.PostForm("code_verifier"))
+	case oauth.GrantClientCredentials:
+		response, err = auth.services.Oauth.ClientCredentials(client, ctx.PostForm("scope"))
+	case oauth.GrantRefreshToken:
+		response, err = auth.services.Oauth.Refresh(client, ctx.PostForm("refresh_token"))
+	default:
+		err = oauth.ErrUnsupportedGrantType
+	}
+	if err != nil {
+		responseOauthError(ctx, err)
+		return
+	}
+	responseOauthData(ctx, response)
+}
+
+// Oauth2Logout 吊销令牌(RFC 7009), 无效的令牌同样返回成功
+//
+// @Summary OAuth2 Revoke
+// @Description token revocation (RFC 7009)
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Router /api/v1/oauth2/revoke [post]
+func (auth AuthController) Oauth2Logout(ctx *gin.Context) {
+	client, ok := auth.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+	if err := auth.services.Oauth.Revoke(client, ctx.PostForm("token")); err != nil {
+		responseOauthError(ctx, err)
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+}
+
+// Oauth2Introspect 令牌内省(RFC 7662), 只允许机密客户端调用, 且只能内省自己的令牌
+//
+// @Summary OAuth2 Introspect
+// @Description token introspection (RFC 7662)
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "token"
+// @Router /api/v1/oauth2/introspect [post]
+func (auth AuthController) Oauth2Introspect(ctx *gin.Context) {
+	client, ok := auth.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+	if client.Public {
+		responseOauthError(ctx, oauth.ErrUnauthorizedClient)
+		return
+	}
+	token := ctx.PostForm("token")
+	if token == "" {
+		responseOauthError(ctx, oauth.ErrInvalidRequest.WithDescription("token is required"))
+		return
+	}
+	responseOauthData(ctx, auth.services.Oauth.Introspect(client, token))
+}
+
+// CreateClient 注册OAuth2客户端, 客户端密钥只在此时返回一次
+//
+// @Summary Create OAuth2 Client
+// @Param request body dto.OauthClientCreateDTO true "client"
+// @Tags oauth2
+// @Accept json
+// @Router /api/v1/oauth2/client/create [post]
+func (auth AuthController) CreateClient(ctx *gin.Context) {
+	param := new(dto.OauthClientCreateDTO)
+	if !bindOauthParams(ctx, param) {
+		return
+	}
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		core.ResponseError(ctx, builtin.ErrUnauthorized)
+		return
+	}
+	client := &model.OauthClientModel{
+		Name:         param.Name,
+		RedirectURIs: strings.Join(param.RedirectURIs, " "),
+		GrantTypes:   strings.Join(param.GrantTypes, " "),
+		Scopes:       strings.Join(param.Scopes, " "),
+		Public:       param.Public,
+		OwnerGID:     claims.GID,
+	}
+	secret, err := auth.services.Oauth.RegisterClient(client, claims)
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	view := clientView(client)
+	if secret != "" {
+		view["client_secret"] = secret
+	}
+	core.ResponseData(ctx, view)
+}
+
+// clientOwner 当前用户可以管理的客户端所属用户, 拥有全部权限的管理员返回 owner, 可以为空表示全部客户端
+//
+// 其他用户只能管理自己创建的客户端, owner 为其他用户时返回 false
+func clientOwner(ctx *gin.Context, owner string) (string, bool) {
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		core.ResponseError(ctx, builtin.ErrUnauthorized)
+		return "", false
+	}
+	if claims.Permissions() == rbac.PermAll {
+		return owner, true
+	}
+	if owner != "" && owner != claims.GID {
+		core.ResponseError(ctx, builtin.ErrForbidden)
+		return "", false
+	}
+	return claims.GID, true
+}
+
+// ListClients OAuth2客户端列表, 默认只返回当前用户创建的客户端, 管理员可以通过 owner 查看指定用户的客户端, 省略时返回全部
+//
+// @Summary List OAuth2 Clients
+// @Param owner query string false "owner gid, admin only"
+// @Tags oauth2
+// @Router /api/v1/oauth2/client/list [get]
+func (auth AuthController) ListClients(ctx *gin.Context) {
+	owner, ok := clientOwner(ctx, ctx.Query("owner"))
+	if !ok {
+		return
+	}
+	clients, err := auth.services.Oauth.ListClients(owner)
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	views := make([]gin.H, 0, len(clients))
+	for _, client := range clients {
+		views = append(views, clientView(client))
+	}
+	core.ResponseData(ctx, views)
+}
+
+// DeleteClient 删除OAuth2客户端, 管理员以外只能删除自己创建的客户端
+//
+// @Summary Delete OAuth2 Client
+// @Param request body dto.OauthClientDeleteDTO true "client"
+// @Tags oauth2
+// @Accept json
+// @Router /api/v1/oauth2/client/delete [delete]
+func (auth AuthController) DeleteClient(ctx *gin.Context) {
+	param := new(dto.OauthClientDeleteDTO)
+	if !bindOauthParams(ctx, param) {
+		return
+	}
+	owner, ok := clientOwner(ctx, "")
+	if !ok {
+		return
+	}
+	if err := auth.services.Oauth.DeleteClient(param.ClientID, owner); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, nil)
+}
+
+func clientView(client *model.OauthClientModel) gin.H {
+	return gin.H{
+		"client_id":     client.ClientID,
+		"name":          client.Name,
+		"redirect_uris": strings.Fields(client.RedirectURIs),
+		"grant_types":   strings.Fields(client.GrantTypes),
+		"scopes":        strings.Fields(client.Scopes),
+		"public":        client.Public,
+		"owner_gid":     client.OwnerGID,
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"net/http"
+	"template/core"
+	"template/internal/builtin"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 保存外部登录状态的 cookie
+const oidcStateCookie = "oidc_state"
+
+
+func setStateCookie(ctx *gin.Context, value string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, value, maxAge, "/api/v1/oauth2/callback", "", ctx.Request.TLS != nil, true)
+}
+
+// Oauth2Providers 可用的外部登录提供方
+//
+// @Summary External Login Providers
+// @Tags oauth2
+// @Router /api/v1/oauth2/providers [get]
+func (auth AuthController) Oauth2Providers(ctx *gin.Context) {
+	providers := auth.services.OIDC.Registry().Providers()
+	views := make([]gin.H, 0, len(providers))
+	for _, provider := range providers {
+		name := provider.Config().Name
+		if name == "" {
+			name = provider.Name
+		}
+		views = append(views, gin.H{
+			"provider": provider.Name,
+			"name":     name,
+			"login":    "/api/v1/oauth2/login/" + provider.Name,
+		})
+	}
+	core.ResponseData(ctx, views)
+}
+
+// Oauth2Login 跳转到外部提供方登录
+//
+// @Summary External Login
+// @Description redirect to external OIDC provider
+// @Param provider path string true "provider"
+// @Tags oauth2
+// @Router /api/v1/oauth2/login/{provider} [get]
+func (auth AuthController) Oauth2Login(ctx *gin.Context) {
+	authURL, state, err := auth.services.OIDC.Begin(ctx.Request.Context(), ctx.Param("provider"))
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	setStateCookie(ctx, state, 600)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// Oauth2Callback 外部提供方登录完成后的回调, 校验身份后签发本系统的令牌
+//
+// @Summary External Login Callback
+// @Param provider path string true "provider"
+// @Param code query string true "authorization code"
+// @Param state query string true "state"
+// @Tags oauth2
+// @Router /api/v1/oauth2/callback/{provider} [get]
+func (auth AuthController) Oauth2Callback(ctx *gin.Context) {
+	stateCookie, _ := ctx.Cookie(oidcStateCookie)
+	// 登录状态只能使用一次
+	setStateCookie(ctx, "", -1)
+	if ctx.Query("error") != "" || stateCookie == "" {
+		core.ResponseError(ctx, builtin.ErrExternalLogin)
+		return
+	}
+
+	account, err := auth.services.OIDC.Complete(ctx.Request.Context(), ctx.Param("provider"), stateCookie, ctx.Query("state"), ctx.Query("code"))
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+
+	accessToken, refreshToken, err := auth.services.Auth.Token(account.Email, account.GID, account.ID)
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	ctx.Writer.Header().Set("Authorization", "Bearer "+accessToken)
+	ctx.Writer.Header().Set("Refresh-Token", refreshToken)
+	core.ResponseData(ctx, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+	})
+}
@@ -0,0 +1,205 @@
+package role
+
+import (
+	"template/core"
+	"template/dto"
+	"template/internal/builtin"
+	"template/model"
+	"template/service"
+	"template/service/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	services *service.Service
+}
+
+// New 使用 services 处理请求
+func New(services *service.Service) RoleController {
+	return RoleController{services: services}
+}
+
+func bindParams[T any](ctx *gin.Context, param *T) bool {
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return false
+	}
+	if err := core.ValidateParams(ctx.Request.Context(), param); err != nil {
+		core.ResponseError(ctx, err)
+		return false
+	}
+	return true
+}
+
+func parsePermissions(ctx *gin.Context, codes []string) (rbac.Permission, bool) {
+	permission, unknown := rbac.ParsePermissions(codes...)
+	if len(unknown) > 0 {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return 0, false
+	}
+	return permission, true
+}
+
+func roleView(role *model.RoleModel) gin.H {
+	return gin.H{
+		"name":        role.Name,
+		"description": role.Description,
+		"permissions": rbac.Permission(role.Permission).Codes(),
+	}
+}
+
+// Create 创建角色
+//
+// @Summary Create Role
+// @Description create role with permission codes
+// @Param request body dto.RoleCreateDTO true "role"
+// @Tags role
+// @Accept json
+// @Router /api/v1/role/create [post]
+func (role RoleController) Create(ctx *gin.Context) {
+	param := new(dto.RoleCreateDTO)
+	if !bindParams(ctx, param) {
+		return
+	}
+	permission, ok := parsePermissions(ctx, param.Permissions)
+	if !ok {
+		return
+	}
+	roleModel := model.NewModel(
+		model.WithRoleName(param.Name),
+		model.WithRoleDescription(param.Description),
+		model.WithRolePermission(int64(permission)),
+	)
+	if err := role.services.RBAC.CreateRole(roleModel); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, roleView(roleModel))
+}
+
+// List 角色列表
+//
+// @Summary List Roles
+// @Tags role
+// @Router /api/v1/role/list [get]
+func (role RoleController) List(ctx *gin.Context) {
+	roles, err := role.services.RBAC.ListRoles()
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	result := make([]gin.H, 0, len(roles))
+	for _, item := range roles {
+		result = append(result, roleView(item))
+	}
+	core.ResponseData(ctx, result)
+}
+
+// Update 修改角色描述和权限
+//
+// @Summary Update Role
+// @Param request body dto.RoleUpdateDTO true "role"
+// @Tags role
+// @Accept json
+// @Router /api/v1/role/update [put]
+func (role RoleController) Update(ctx *gin.Context) {
+	param := new(dto.RoleUpdateDTO)
+	if !bindParams(ctx, param) {
+		return
+	}
+	permission, ok := parsePermissions(ctx, param.Permissions)
+	if !ok {
+		return
+	}
+	roleModel := model.NewModel(
+		model.WithRoleName(param.Name),
+		model.WithRoleDescription(param.Description),
+		model.WithRolePermission(int64(permission)),
+	)
+	if err := role.services.RBAC.UpdateRole(roleModel); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, roleView(roleModel))
+}
+
+// Delete 删除角色, 同时撤销所有账号的该角色
+//
+// @Summary Delete Role
+// @Param name query string true "role name"
+// @Tags role
+// @Router /api/v1/role/delete [delete]
+func (role RoleController) Delete(ctx *gin.Context) {
+	name := ctx.Query("name")
+	if name == "" {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return
+	}
+	if err := role.services.RBAC.DeleteRole(name); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, nil)
+}
+
+// Grant 为账号授予角色
+//
+// @Summary Grant Role
+// @Param request body dto.RoleGrantDTO true "grant"
+// @Tags role
+// @Accept json
+// @Router /api/v1/role/grant [post]
+func (role RoleController) Grant(ctx *gin.Context) {
+	param := new(dto.RoleGrantDTO)
+	if !bindParams(ctx, param) {
+		return
+	}
+	if err := role.services.RBAC.Grant(param.GID, param.Role); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, nil)
+}
+
+// Revoke 撤销账号的角色
+//
+// @Summary Revoke Role
+// @Param request body dto.RoleGrantDTO true "revoke"
+// @Tags role
+// @Accept json
+// @Router /api/v1/role/revoke [post]
+func (role RoleController) Revoke(ctx *gin.Context) {
+	param := new(dto.RoleGrantDTO)
+	if !bindParams(ctx, param) {
+		return
+	}
+	if err := role.services.RBAC.Revoke(param.GID, param.Role); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, nil)
+}
+
+// Account 查看账号的角色和权限
+//
+// @Summary Account Roles
+// @Param gid query string true "account gid"
+// @Tags role
+// @Router /api/v1/role/account [get]
+func (role RoleController) Account(ctx *gin.Context) {
+	gid := ctx.Query("gid")
+	if gid == "" {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return
+	}
+	roles, permission, err := role.services.RBAC.Grants(gid)
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, gin.H{
+		"roles":       roles,
+		"permissions": permission.Codes(),
+	})
+}
@@ -1,21 +1,178 @@
 package user
 
-import "github.com/gin-gonic/gin"
+import (
+	"template/common/filter"
+	"template/core"
+	"template/dto"
+	"template/internal/builtin"
+	"template/middleware"
+	"template/model"
+	"template/service"
+	"template/service/rbac"
 
-type UserController struct {}
+	"github.com/gin-gonic/gin"
+)
 
+type UserController struct {
+	services *service.Service
+}
+
+// New 使用 services 处理请求
+func New(services *service.Service) UserController {
+	return UserController{services: services}
+}
+
+func bindParams[T any](ctx *gin.Context, param *T) bool {
+	if err := ctx.ShouldBindJSON(param); err != nil {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return false
+	}
+	if err := core.ValidateParams(ctx.Request.Context(), param); err != nil {
+		core.ResponseError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// canManage 本人或拥有 code 权限时可以操作该用户
+//
+// 第三方客户端代表本人操作时, 用户还需要授权了包含 code 的 scope
+func canManage(ctx *gin.Context, gid string, code string) bool {
+	claims, ok := middleware.GetClaims(ctx)
+	if !ok {
+		core.ResponseError(ctx, builtin.ErrUnauthorized)
+		return false
+	}
+	required := rbac.MustParsePermissions(code)
+	self := claims.GID == gid && (claims.ClientID == "" || claims.ScopePermissions().Has(required))
+	if self || claims.Permissions().Has(required) {
+		return true
+	}
+	core.ResponseError(ctx, builtin.ErrForbidden)
+	return false
+}
+
+// CreateUser 注册用户, 同时创建登录账号
+//
+// @Summary Create User
+// @Description create account and user in one transaction
+// @Param request body dto.UserCreateDTO true "user"
+// @Tags user
+// @Accept json
+// @Router /api/v1/user/create [post]
 func (user UserController) CreateUser(ctx *gin.Context){
+	param := new(dto.UserCreateDTO)
+	if !bindParams(ctx, param) {
+		return
+	}
+	userModel := model.NewModel(
+		model.WithUserName(param.Name),
+	)
+	userModel.Email = param.Email
+	userModel.Country = param.Country
+	accountModel := model.NewModel(
+		model.WithAccountPassword(param.Password),
+	)
+	accountModel.Email = param.Email
 
+	if err := user.services.User.Register(ctx.Request.Context(), userModel, accountModel); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, filter.GORMFieldsFilter(userModel))
 }
 
+// List 用户列表, 支持过滤、排序和分页, 携带 cursor 参数时使用游标分页
+//
+// @Summary List Users
+// @Description filter[email][like]=foo&filter[or][name]=a&filter[or][country][in]=CN,US&sort=-created_at
+// @Param page query int false "page"
+// @Param size query int false "size"
+// @Param sort query string false "sort fields, prefix - for descending"
+// @Param cursor query string false "empty for the first page, then next or prev from the response"
+// @Tags user
+// @Router /api/v1/user/list [get]
 func (user UserController) List(ctx *gin.Context){
-
+	q, err := dto.UserQuery.ParseValues(ctx.Request.URL.Query())
+	if err != nil {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return
+	}
+	if q.Keyset {
+		users, cursors, err := user.services.User.Seek(q)
+		if err != nil {
+			core.ResponseError(ctx, err)
+			return
+		}
+		core.ResponsePage(ctx, filter.FilterResult(users), core.WithCursors(q.Size, cursors))
+		return
+	}
+	users, total, err := user.services.User.List(q)
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponsePage(ctx, filter.FilterResult(users), core.WithOffset(total, q.Page, q.Size))
 }
 
+// Update 部分更新用户信息, 只能修改本人或拥有 user:write 权限
+//
+// @Summary Update User
+// @Param request body dto.UserUpdateDTO true "user"
+// @Tags user
+// @Accept json
+// @Router /api/v1/user/update [put]
 func (user UserController) Update(ctx *gin.Context){
+	param := new(dto.UserUpdateDTO)
+	if !bindParams(ctx, param) || !canManage(ctx, param.GID, "user:write") {
+		return
+	}
+	fields := make(map[string]any)
+	for column, value := range map[string]*string{
+		"name":         param.Name,
+		"description":  param.Description,
+		"phone":        param.Phone,
+		"country_code": param.Country,
+		"gender":       param.Gender,
+		"avatar":       param.Avatar,
+		"language":     param.Language,
+	} {
+		if value != nil {
+			fields[column] = *value
+		}
+	}
+	if len(fields) == 0 {
+		core.ResponseError(ctx, builtin.ErrInvalidParams)
+		return
+	}
 
+	if err := user.services.User.Patch(param.GID, param.Version, fields); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	updated, err := user.services.User.GetByGID(param.GID)
+	if err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, filter.GORMFieldsFilter(updated))
 }
 
+// Delete 软删除用户及其账号, 只能删除本人或拥有 user:delete 权限
+//
+// @Summary Delete User
+// @Param request body dto.UserDeleteDTO true "user"
+// @Tags user
+// @Accept json
+// @Router /api/v1/user/delete [delete]
 func (user UserController) Delete(ctx *gin.Context){
-
+	param := new(dto.UserDeleteDTO)
+	if !bindParams(ctx, param) || !canManage(ctx, param.GID, "user:delete") {
+		return
+	}
+	if err := user.services.User.Delete(ctx.Request.Context(), param.GID); err != nil {
+		core.ResponseError(ctx, err)
+		return
+	}
+	core.ResponseData(ctx, nil)
 }
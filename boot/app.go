@@ -0,0 +1,149 @@
+package boot
+
+import (
+	"fmt"
+	api "template/api/v1"
+	"template/dao"
+	"template/global/config"
+	"template/global/database"
+	"template/global/logger"
+	"template/i18n"
+	"template/middleware"
+	"template/router"
+	"template/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// App 应用的所有依赖, 按 配置 → 日志 → 数据库 → i18n → 服务 → 控制器 → 路由 的顺序创建
+type App struct {
+	Config  *config.Configure
+	Sources config.Sources
+	// Reloader 配置来自分层加载时用于重新加载, 否则为空
+	Reloader *config.Reloader
+	Logger   *zap.Logger
+	DB       *gorm.DB
+	Dao      *dao.Dao
+	Services *service.Service
+	API      *api.API
+	Router   *gin.Engine
+	// RateLimit 根路由的限流速率, 可以随配置重新加载修改
+	RateLimit *middleware.RateLimit
+
+	// flags 加载配置时使用的命令行参数
+	flags *config.Flags
+}
+
+type AppOption func(app *App)
+
+// WithConfig 使用 conf, 不再分层加载配置
+func WithConfig(conf *config.Configure) AppOption {
+	return func(app *App) {
+		app.Config = conf
+	}
+}
+
+// WithFlags 加载配置时使用的命令行参数
+func WithFlags(flags *config.Flags) AppOption {
+	return func(app *App) {
+		app.flags = flags
+	}
+}
+
+// WithLogger 使用 logger, 不再按配置创建
+func WithLogger(logger *zap.Logger) AppOption {
+	return func(app *App) {
+		app.Logger = logger
+	}
+}
+
+// WithDB 使用 db, 不再按配置连接数据库
+func WithDB(db *gorm.DB) AppOption {
+	return func(app *App) {
+		app.DB = db
+	}
+}
+
+// WithServices 使用 services, 用于在测试中替换为假的实现
+func WithServices(services *service.Service) AppOption {
+	return func(app *App) {
+		app.Services = services
+	}
+}
+
+// NewApp 创建应用, 通过 opts 提供的依赖不再创建
+func NewApp(opts ...AppOption) (*App, error) {
+	app := new(App)
+	for _, opt := range opts {
+		opt(app)
+	}
+
+	if app.Config == nil {
+		conf, sources, err := config.Load(config.WithFlags(app.flags))
+		if err != nil {
+			return nil, err
+		}
+		app.Config, app.Sources = conf, sources
+		app.Reloader = config.NewReloader(conf, config.WithFlags(app.flags))
+	}
+
+	if app.Logger == nil {
+		zapLogger, err := logger.NewLogger(app.Config.System)
+		if err != nil {
+			return nil, fmt.Errorf("boot: %w", err)
+		}
+		app.Logger = zapLogger
+	}
+	app.Logger.Debug("configure loaded", zap.Object("configure", app.Config))
+
+	if app.DB == nil {
+		if app.DB = database.CreateConnect(app.Config.Database); app.DB == nil {
+			return nil, fmt.Errorf("boot: connect %q database failed", app.Config.Database.Type)
+		}
+	}
+
+	if err := i18n.Load(app.Config.System.Locale, app.Config.Env.LoggerLanguage); err != nil {
+		return nil, fmt.Errorf("boot: load locale: %w", err)
+	}
+
+	// 游标签名密钥由 HKDF 派生, 未单独配置时与令牌签名共用 system.user.sign
+	cursorSecret := app.Config.System.CursorSecret
+	if cursorSecret == "" {
+		cursorSecret = app.Config.System.User.Sign
+	}
+	d, err := dao.New(app.DB, cursorSecret)
+	if err != nil {
+		return nil, fmt.Errorf("boot: create dao: %w", err)
+	}
+	app.Dao = d
+	if app.Services == nil {
+		services, err := service.New(app.Dao, app.Config, app.Logger)
+		if err != nil {
+			return nil, fmt.Errorf("boot: create services: %w", err)
+		}
+		app.Services = services
+	}
+
+	app.API = api.New(app.Services)
+	if limit := app.Config.System.RateLimit; limit.QPS > 0 && limit.Burst > 0 {
+		app.RateLimit = middleware.NewRateLimit(limit.QPS, limit.Burst)
+	}
+	if app.Router, err = router.Routers(app.API, app.Services, app.Config, app.Logger, app.RateLimit); err != nil {
+		return nil, fmt.Errorf("boot: create router: %w", err)
+	}
+	if app.Reloader != nil {
+		app.subscribe()
+	}
+	return app, nil
+}
+
+// Close 关闭数据库连接
+func (app *App) Close() error {
+	conn, err := app.DB.DB()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
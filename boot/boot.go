@@ -7,28 +7,38 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"template/global"
-	"template/router"
 	"time"
 
 	"go.uber.org/zap"
 )
 
-func Startup() {
+func Startup(opts ...AppOption) {
+	app, err := NewApp(opts...)
+	if err != nil {
+		exit(fmt.Errorf("startup failed:\n%w", err))
+	}
+	if err := app.migrate(); err != nil {
+		app.Logger.Error("migrate database failed", zap.Error(err))
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
-	if err := runHooks(hookPreStart, map[string]string{
+	// SIGHUP 和配置文件变化时重新加载配置
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	app.watch(watchCtx)
+
+	if err := app.runHooks(hookPreStart, map[string]string{
 		"PID":  fmt.Sprintf("%d", os.Getpid()),
-		"PORT": fmt.Sprintf("%d", global.Config.Env.Port),
+		"PORT": fmt.Sprintf("%d", app.Config.Env.Port),
 	}, HookModeLenient); err != nil {
-		global.Logger.Error("run hooks failed", zap.Error(err))
+		app.Logger.Error("run hooks failed", zap.Error(err))
 	}
 
-	app := router.Routers()
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", global.Config.Env.Port),
-		Handler: app,
+		Addr:    fmt.Sprintf(":%d", app.Config.Env.Port),
+		Handler: app.Router,
 	}
 
 	go func() {
@@ -38,17 +48,18 @@ func Startup() {
 		}
 	}()
 
-	if err := runHooks(hookStarted, map[string]string{
+	if err := app.runHooks(hookStarted, map[string]string{
 		"PID":  fmt.Sprintf("%d", os.Getpid()),
-		"PORT": fmt.Sprintf("%d", global.Config.Env.Port),
+		"PORT": fmt.Sprintf("%d", app.Config.Env.Port),
 	}, HookModeLenient); err != nil {
-		global.Logger.Error("run hooks failed", zap.Error(err))
+		app.Logger.Error("run hooks failed", zap.Error(err))
 	}
 	sig := <-stop
-	if err := runHooks(hookExit, map[string]string{
+	stopWatch()
+	if err := app.runHooks(hookExit, map[string]string{
 		"signal": fmt.Sprintf("%s", sig),
 	}, HookModeLenient); err != nil {
-		global.Logger.Error("run hooks failed", zap.Error(err))
+		app.Logger.Error("run hooks failed", zap.Error(err))
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -58,11 +69,8 @@ func Startup() {
 		fmt.Println("HTTP server shutdown successfully")
 	}
 
-	if global.DB != nil {
-		if conn, err := global.DB.DB(); err == nil {
-			_ = conn.Close()
-			fmt.Println("Database connection closed")
-		}
+	if err := app.Close(); err == nil {
+		fmt.Println("Database connection closed")
 	}
 
 	os.Exit(0)
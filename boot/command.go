@@ -0,0 +1,90 @@
+package boot
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"template/common/codec"
+	"template/global/config"
+)
+
+// Execute 按命令行参数运行
+//
+//	template [-config path] [-mode mode] [-set key=value ...]        启动服务
+//	template config export [-format json|yaml|toml] [-sources] [-config path] [-set ...]  写出合并后的配置, 隐藏敏感配置项
+//	template config encrypt < secret                                                     使用主密钥加密, 输出 enc: 值
+func Execute(args []string) {
+	if len(args) >= 2 && args[0] == "config" {
+		var err error
+		switch args[1] {
+		case "export":
+			err = exportConfigure(os.Stdout, args[2:])
+		case "encrypt":
+			err = encryptSecret(os.Stdout, os.Stdin)
+		default:
+			err = fmt.Errorf("unknown command: config %s", args[1])
+		}
+		if err != nil {
+			exit(err)
+		}
+		return
+	}
+
+	fs, flags := config.NewFlagSet("template")
+	if err := fs.Parse(args); err != nil {
+		exit(err)
+	}
+	Startup(WithFlags(flags))
+}
+
+// exportConfigure 代替启动时写回配置文件, 需要时可以重定向到文件
+func exportConfigure(w io.Writer, args []string) error {
+	fs, flags := config.NewFlagSet("config export")
+	withSources := fs.Bool("sources", false, "按配置项写出取值和来源")
+	formatName := fs.String("format", string(codec.JSON), "输出格式 json, yaml 或 toml")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	format, err := codec.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	conf, sources, err := config.Load(config.WithFlags(flags))
+	if err != nil {
+		return err
+	}
+	if *withSources {
+		return config.ExportSources(w, conf, sources)
+	}
+	return config.Export(w, conf, format)
+}
+
+// encryptSecret 使用 config.MasterKeyEnv 中的主密钥加密 r 的内容, 末尾的换行不加密
+func encryptSecret(w io.Writer, r io.Reader) error {
+	key, err := config.ParseMasterKey(os.Getenv(config.MasterKeyEnv))
+	if err != nil {
+		return err
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	value, err := config.Encrypt(key, strings.TrimRight(string(plaintext), "\r\n"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, value)
+	return err
+}
+
+// exit 输出错误后退出, -h 显示帮助后正常退出
+func exit(err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -1,13 +1,25 @@
 package boot
 
 import (
-	"template/global"
 	"template/model"
 )
 
-func database_boot(){
-	global.DB.AutoMigrate(
+// migrate 同步表结构和代码中注册的权限
+func (app *App) migrate() error {
+	err := app.DB.AutoMigrate(
 		&model.UserModel{},
 		&model.AccountModel{},
+		&model.RoleModel{},
+		&model.PermissionModel{},
+		&model.AccountRoleModel{},
+		&model.RefreshTokenModel{},
+		&model.TokenDenylistModel{},
+		&model.OauthClientModel{},
+		&model.OauthCodeModel{},
+		&model.AccountIdentityModel{},
 	)
+	if err != nil {
+		return err
+	}
+	return app.Services.RBAC.SyncPermissions()
 }
\ No newline at end of file
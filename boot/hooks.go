@@ -11,7 +11,6 @@ import (
 	"sort"
 	"strings"
 	"sync"
-	"template/global"
 	"time"
 
 	"go.uber.org/zap"
@@ -78,7 +77,7 @@ func hookDir() string {
 	return defaultHookDir
 }
 
-func runHooks(phase string, extraEnv map[string]string, mode HookMode) error {
+func (app *App) runHooks(phase string, extraEnv map[string]string, mode HookMode) error {
 	path := filepath.Join(hookDir(), phase)
 
 	info, err := os.Stat(path)
@@ -93,7 +92,7 @@ func runHooks(phase string, extraEnv map[string]string, mode HookMode) error {
 
 	if info.Mode().IsRegular() {
 		// 如果是文件，直接执行
-		return runHookScript(path, phase, extraEnv, mode)
+		return app.runHookScript(path, phase, extraEnv, mode)
 	}
 
 	if info.IsDir() {
@@ -115,7 +114,7 @@ func runHooks(phase string, extraEnv map[string]string, mode HookMode) error {
 			wg.Add(1)
 			go func(script string) {
 				defer wg.Done()
-				if err := runHookScript(script, phase, extraEnv, mode); err != nil {
+				if err := app.runHookScript(script, phase, extraEnv, mode); err != nil {
 					errCh <- err
 				}
 			}(script)
@@ -171,7 +170,7 @@ func buildHookCommand(ctx context.Context, path, ext string, info os.FileInfo) (
 	return nil, fmt.Errorf("unsupported hook file: %s", path)
 }
 
-func runHookScript(path string, phase string, extraEnv map[string]string, mode HookMode) error {
+func (app *App) runHookScript(path string, phase string, extraEnv map[string]string, mode HookMode) error {
 	info, err := os.Stat(path)
 	if err != nil {
 		return fmt.Errorf("stat %s: %w", path, err)
@@ -181,14 +180,14 @@ func runHookScript(path string, phase string, extraEnv map[string]string, mode H
 	}
 
 	ext := strings.ToLower(filepath.Ext(path))
-	global.Logger.Info("hook", zap.String("phase", phase), zap.String("path", path))
+	app.Logger.Info("hook", zap.String("phase", phase), zap.String("path", path))
 
 	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout())
 	defer cancel()
 
 	cmd, err := buildHookCommand(ctx, path, ext, info)
 	if err != nil {
-		global.Logger.Error("build hook command failed", zap.Error(err))
+		app.Logger.Error("build hook command failed", zap.Error(err))
 		return err
 	}
 	// 注入环境变量
@@ -207,7 +206,7 @@ func runHookScript(path string, phase string, extraEnv map[string]string, mode H
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
 		}
-		global.Logger.Error("hook",
+		app.Logger.Error("hook",
 			zap.String("phase", phase),
 			zap.String("path", path),
 			zap.Int("exit_code", exitCode),
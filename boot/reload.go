@@ -0,0 +1,73 @@
+package boot
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	parseduration "template/common/parseDuration"
+	"template/global/config"
+	"template/global/logger"
+
+	"go.uber.org/zap"
+)
+
+// subscribe 配置重新加载后更新日志级别、令牌有效期和限流速率
+func (app *App) subscribe() {
+	app.Reloader.Subscribe(func(old, next *config.Configure) error {
+		return logger.SetLevel(next.System.LoggerLever)
+	})
+	app.Reloader.Subscribe(func(old, next *config.Configure) error {
+		app.Services.Auth.SetTokenDurations(next.System.Token)
+		return nil
+	})
+	app.Reloader.Subscribe(func(old, next *config.Configure) error {
+		if app.RateLimit != nil {
+			app.RateLimit.Set(next.System.RateLimit.QPS, next.System.RateLimit.Burst)
+		}
+		return nil
+	})
+}
+
+// logReload 记录重新加载的结果
+func (app *App) logReload(changed []string, err error) {
+	switch {
+	case err != nil:
+		app.Logger.Error("reload configure failed", zap.Error(err))
+	case len(changed) > 0:
+		app.Logger.Info("configure reloaded", zap.Strings("changed", changed))
+	}
+}
+
+// watch 收到 SIGHUP 或配置文件变化时重新加载配置, 直到 ctx 结束
+//
+// 只有配置了 system.watch_interval 时才轮询配置文件
+func (app *App) watch(ctx context.Context) {
+	if app.Reloader == nil {
+		return
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				app.logReload(app.Reloader.Reload())
+			}
+		}
+	}()
+
+	if app.Config.System.WatchInterval == "" {
+		return
+	}
+	interval, err := parseduration.ParseDuration(app.Config.System.WatchInterval)
+	if err != nil || interval <= 0 {
+		app.Logger.Error("invalid watch interval", zap.String("watch_interval", app.Config.System.WatchInterval), zap.Error(err))
+		return
+	}
+	go app.Reloader.Watch(ctx, interval, app.logReload)
+}
@@ -0,0 +1,160 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format 配置文件和语言文件的格式
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+var ErrUnknownFormat = errors.New("codec: unknown format")
+
+// extensions 扩展名对应的格式
+var extensions = map[string]Format{
+	".json": JSON,
+	".yaml": YAML,
+	".yml":  YAML,
+	".toml": TOML,
+}
+
+// FormatOf 按扩展名判断文件格式
+func FormatOf(path string) (Format, error) {
+	if format, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, filepath.Ext(path))
+}
+
+// ParseFormat 解析格式名称, 也可以是扩展名
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+	return FormatOf(name)
+}
+
+// Decode 将 data 解析到 out
+//
+// YAML 和 TOML 先解析为通用的对象再经过 JSON 转换, 所有格式都按 json 标签映射字段
+func Decode(format Format, data []byte, out any) error {
+	var node map[string]any
+	switch format {
+	case JSON:
+		return json.Unmarshal(data, out)
+	case YAML:
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+	case TOML:
+		if err := toml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Encode 按 json 标签编码 value
+//
+// YAML 保留字段顺序; TOML 没有 null, 值为 null 的字段会被省略
+func Encode(format Format, value any) ([]byte, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case JSON:
+		return append(data, '\n'), nil
+	case YAML:
+		// JSON 也是合法的 YAML, 解析为 yaml.Node 可以保留字段顺序
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		clearStyle(&node)
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), encoder.Close()
+	case TOML:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var node map[string]any
+		if err := decoder.Decode(&node); err != nil {
+			return nil, err
+		}
+		return toml.Marshal(tomlValue(node))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// yaml11Bools YAML 1.1 中的布尔值, 编码器按 1.2 不加引号, 其他解析器可能读成布尔值
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true, "off": true, "Off": true, "OFF": true,
+}
+
+// clearStyle 去掉从 JSON 解析得到的流式风格和引号, 输出块风格的 YAML, 需要时编码器会重新加上引号
+func clearStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	if !(node.Kind == yaml.ScalarNode && node.Tag == "!!str" && yaml11Bools[node.Value]) {
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// tomlValue 删除值为 null 的字段和数组元素, 整数保持为整数
+func tomlValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if child == nil {
+				delete(value, key)
+				continue
+			}
+			value[key] = tomlValue(child)
+		}
+	case []any:
+		items := value[:0]
+		for _, child := range value {
+			if child != nil {
+				items = append(items, tomlValue(child))
+			}
+		}
+		return items
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number
+		}
+		number, _ := value.Float64()
+		return number
+	}
+	return value
+}
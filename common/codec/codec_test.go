@@ -0,0 +1,174 @@
+package codec_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"template/common/codec"
+	"template/global/config"
+	"template/i18n"
+	"testing"
+)
+
+var formats = []codec.Format{codec.JSON, codec.YAML, codec.TOML}
+
+// trickyStrings 在 YAML 中不加引号会被解析为其他类型或结构的字符串
+var trickyStrings = []string{"true", "no", "yes", "on", "null", "~", "123", "1.5", "1e3", "0x1F", "2024-01-02", "", " padded ", "a: b", "#comment", "- item", "[1]", "{a}", "*ref", "multi\nline", "tab\tquote\""}
+
+func testConfigure() *config.Configure {
+	conf := config.Default()
+	conf.Database = config.Database{Type: "postgres", Username: "app", Password: "123", Host: "db", Port: 5432, DBName: "true"}
+	conf.System.Language = "zh-CN"
+	conf.System.User.Sign = "null"
+	conf.System.Version.System = "1.0"
+	conf.System.HideVersion = true
+	conf.System.RateLimit = config.RateLimitConfig{QPS: 2.5, Burst: 10}
+	conf.System.Token.Keys = []config.SigningKeyConfig{
+		{ID: "2024", Algorithm: "RS256", Path: "keys/2024.pem", NotBefore: "2024-01-02T00:00:00Z"},
+		{ID: "yes", Path: "keys/next.pem"},
+	}
+	conf.System.OIDC = config.OIDCConfig{
+		"google": {
+			Name:        "Google",
+			Issuer:      "https://accounts.google.com",
+			ClientID:    "0123",
+			RedirectURL: "http://localhost:8080/api/v1/oauth2/callback/google",
+			Scopes:      []string{"email", "profile"},
+			AutoSignup:  true,
+		},
+	}
+	return conf
+}
+
+func TestRoundTripConfigure(t *testing.T) {
+	want := testConfigure()
+	for _, format := range formats {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := codec.Encode(format, want)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got := new(config.Configure)
+			if err := codec.Decode(format, data, got); err != nil {
+				t.Fatalf("Decode: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip changed the configure:\n got %+v\nwant %+v\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestRoundTripLocale(t *testing.T) {
+	data, err := os.ReadFile("../../locale/en.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := new(i18n.Locale)
+	if err := codec.Decode(codec.JSON, data, want); err != nil {
+		t.Fatalf("Decode en.json: %v", err)
+	}
+	for _, format := range formats {
+		t.Run(string(format), func(t *testing.T) {
+			encoded, err := codec.Encode(format, want)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got := new(i18n.Locale)
+			if err := codec.Decode(format, encoded, got); err != nil {
+				t.Fatalf("Decode: %v\n%s", err, encoded)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round trip changed the locale\n%s", encoded)
+			}
+		})
+	}
+}
+
+func TestYAMLQuoting(t *testing.T) {
+	type document struct {
+		Values []string          `json:"values"`
+		Keyed  map[string]string `json:"keyed"`
+	}
+	want := document{Values: trickyStrings, Keyed: map[string]string{}}
+	for _, value := range trickyStrings {
+		want.Keyed[value] = value
+	}
+
+	data, err := codec.Encode(codec.YAML, want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// 输出块风格的 YAML, 不是 JSON
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) || bytes.Contains(data, []byte(`values: [`)) {
+		t.Fatalf("YAML uses flow style:\n%s", data)
+	}
+	for _, line := range []string{`- "true"`, `- "no"`, `- "yes"`, `- "on"`, `- "123"`, `- "null"`, `- "2024-01-02"`, `"on": "on"`} {
+		if !bytes.Contains(data, []byte(line)) {
+			t.Errorf("missing quoted scalar %s in\n%s", line, data)
+		}
+	}
+
+	var got document
+	if err := codec.Decode(codec.YAML, data, &got); err != nil {
+		t.Fatalf("Decode: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip changed the values:\n got %q\nwant %q\n%s", got, want, data)
+	}
+}
+
+func TestTOMLDropsNull(t *testing.T) {
+	value := map[string]any{
+		"name":    "app",
+		"missing": nil,
+		"count":   3,
+		"ratio":   0.5,
+		"items":   []any{"a", nil, "b"},
+		"nested":  map[string]any{"keep": true, "drop": nil},
+		"tables":  []any{map[string]any{"id": 1, "drop": nil}, nil},
+	}
+	data, err := codec.Encode(codec.TOML, value)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if bytes.Contains(data, []byte("missing")) || bytes.Contains(data, []byte("drop")) {
+		t.Fatalf("null values are not dropped:\n%s", data)
+	}
+
+	var got map[string]any
+	if err := codec.Decode(codec.TOML, data, &got); err != nil {
+		t.Fatalf("Decode: %v\n%s", err, data)
+	}
+	want := map[string]any{
+		"name":   "app",
+		"count":  float64(3),
+		"ratio":  0.5,
+		"items":  []any{"a", "b"},
+		"nested": map[string]any{"keep": true},
+		"tables": []any{map[string]any{"id": float64(1)}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v\n%s", got, want, data)
+	}
+	// 整数保持为整数
+	if !bytes.Contains(data, []byte("count = 3\n")) {
+		t.Fatalf("integer is not kept:\n%s", data)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	cases := map[string]codec.Format{"config.json": codec.JSON, "a/b.YML": codec.YAML, "c.yaml": codec.YAML, "d.toml": codec.TOML}
+	for path, want := range cases {
+		if got, err := codec.FormatOf(path); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if _, err := codec.FormatOf("config.ini"); !errors.Is(err, codec.ErrUnknownFormat) {
+		t.Errorf("FormatOf(config.ini) err = %v, want %v", err, codec.ErrUnknownFormat)
+	}
+	if _, err := codec.Encode("xml", nil); !errors.Is(err, codec.ErrUnknownFormat) {
+		t.Errorf("Encode(xml) err = %v, want %v", err, codec.ErrUnknownFormat)
+	}
+}
@@ -666,6 +666,15 @@ func (d *DotENV) Get(field string) any {
 	return nil
 }
 
+// Values 所有变量, 嵌套的 key 用分隔符连接
+func (d *DotENV) Values() map[string]any {
+	values := d.flattenNestedDict(d.env)
+	for name, value := range d.exports {
+		values[name] = value
+	}
+	return values
+}
+
 func (d *DotENV) Load(text string) {
 	d.parse(d.tokenize(text))
 }
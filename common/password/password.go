@@ -0,0 +1,102 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"template/global/config"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("password: unknown hash algorithm")
+	ErrInvalidHash      = errors.New("password: invalid encoded hash")
+)
+
+// Hasher 密码哈希算法, 编码后的哈希串中包含算法参数
+type Hasher interface {
+	// ID 算法名称
+	ID() string
+	// Hash 生成编码后的哈希串
+	Hash(password string) (string, error)
+	// Verify 以常量时间比较密码与哈希串
+	Verify(password, encoded string) (bool, error)
+	// Match 哈希串是否由该算法生成
+	Match(encoded string) bool
+	// NeedsRehash 哈希串的参数与当前配置不一致, 需要重新计算
+	NeedsRehash(encoded string) bool
+}
+
+// Manager 使用当前算法生成哈希, 根据哈希串自动选择算法校验
+type Manager struct {
+	current         Hasher
+	hashers         []Hasher
+	rejectPlaintext bool
+}
+
+// NewManager current 用于生成新哈希, others 仅用于校验旧哈希
+func NewManager(current Hasher, others ...Hasher) *Manager {
+	return &Manager{
+		current: current,
+		hashers: append([]Hasher{current}, others...),
+	}
+}
+
+// New 根据配置创建 Manager, 未配置时默认使用 argon2id
+func New(conf config.PasswordConfig) *Manager {
+	bcryptHasher := NewBcrypt(conf.BcryptCost)
+	argon2Hasher := NewArgon2id(conf.Argon2Memory, conf.Argon2Time, conf.Argon2Threads)
+
+	var manager *Manager
+	switch conf.Algorithm {
+	case AlgorithmBcrypt:
+		manager = NewManager(bcryptHasher, argon2Hasher)
+	default:
+		manager = NewManager(argon2Hasher, bcryptHasher)
+	}
+	manager.rejectPlaintext = conf.RejectPlaintext
+	return manager
+}
+
+// Hash 使用当前算法生成哈希
+func (m *Manager) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+// Verify 校验密码, rehash 为 true 时调用方应使用 Hash 重新生成并保存
+//
+// 无法识别的哈希串视为历史遗留的明文密码, 校验通过后同样要求重新生成
+func (m *Manager) Verify(password, encoded string) (ok bool, rehash bool, err error) {
+	if encoded == "" {
+		return false, false, nil
+	}
+	for _, hasher := range m.hashers {
+		if !hasher.Match(encoded) {
+			continue
+		}
+		ok, err = hasher.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, hasher.ID() != m.current.ID() || hasher.NeedsRehash(encoded), nil
+	}
+
+	if m.rejectPlaintext {
+		return false, false, ErrUnknownAlgorithm
+	}
+	ok = subtle.ConstantTimeCompare([]byte(password), []byte(encoded)) == 1
+	return ok, ok, nil
+}
+
+// IsHashed 哈希串是否由已知算法生成
+func (m *Manager) IsHashed(encoded string) bool {
+	for _, hasher := range m.hashers {
+		if hasher.Match(encoded) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"template/global/config"
+	"testing"
+)
+
+// 测试使用较小的参数, 避免哈希计算拖慢测试
+var testConfig = config.PasswordConfig{BcryptCost: 4, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}
+
+func TestHashEncoding(t *testing.T) {
+	tests := map[string]struct {
+		hasher Hasher
+		prefix string
+	}{
+		"argon2id": {NewArgon2id(1024, 1, 1), "$argon2id$v=19$m=1024,t=1,p=1$"},
+		"bcrypt":   {NewBcrypt(4), "$2a$04$"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := test.hasher.Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !strings.HasPrefix(encoded, test.prefix) {
+				t.Fatalf("Hash = %q, want prefix %q", encoded, test.prefix)
+			}
+			if !test.hasher.Match(encoded) {
+				t.Fatalf("Match(%q) = false", encoded)
+			}
+			if again, _ := test.hasher.Hash("secret"); again == encoded {
+				t.Fatal("two hashes of the same password are equal, salt is not random")
+			}
+			if ok, err := test.hasher.Verify("secret", encoded); err != nil || !ok {
+				t.Fatalf("Verify(correct) = %v, %v", ok, err)
+			}
+			if ok, err := test.hasher.Verify("wrong", encoded); err != nil || ok {
+				t.Fatalf("Verify(wrong) = %v, %v", ok, err)
+			}
+			if test.hasher.NeedsRehash(encoded) {
+				t.Fatal("NeedsRehash of a fresh hash = true")
+			}
+		})
+	}
+}
+
+func TestVerifyRehash(t *testing.T) {
+	argon2Hash, _ := NewArgon2id(1024, 1, 1).Hash("secret")
+	weakArgon2Hash, _ := NewArgon2id(512, 1, 1).Hash("secret")
+	bcryptHash, _ := NewBcrypt(4).Hash("secret")
+	weakBcryptHash, _ := NewBcrypt(5).Hash("secret")
+
+	argon2Config := testConfig
+	bcryptConfig := testConfig
+	bcryptConfig.Algorithm = AlgorithmBcrypt
+
+	tests := map[string]struct {
+		conf     config.PasswordConfig
+		encoded  string
+		password string
+		ok       bool
+		rehash   bool
+	}{
+		"current argon2id":          {argon2Config, argon2Hash, "secret", true, false},
+		"argon2id params changed":   {argon2Config, weakArgon2Hash, "secret", true, true},
+		"bcrypt under argon2id":     {argon2Config, bcryptHash, "secret", true, true},
+		"current bcrypt":            {bcryptConfig, bcryptHash, "secret", true, false},
+		"bcrypt cost changed":       {bcryptConfig, weakBcryptHash, "secret", true, true},
+		"argon2id under bcrypt":     {bcryptConfig, argon2Hash, "secret", true, true},
+		"wrong password":            {argon2Config, argon2Hash, "wrong", false, false},
+		"legacy plaintext":          {argon2Config, "secret", "secret", true, true},
+		"legacy plaintext mismatch": {argon2Config, "secret", "wrong", false, false},
+		"empty hash":                {argon2Config, "", "", false, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ok, rehash, err := New(test.conf).Verify(test.password, test.encoded)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if ok != test.ok || rehash != test.rehash {
+				t.Fatalf("Verify = %v, %v, want %v, %v", ok, rehash, test.ok, test.rehash)
+			}
+		})
+	}
+}
+
+func TestRejectPlaintext(t *testing.T) {
+	conf := testConfig
+	conf.RejectPlaintext = true
+	manager := New(conf)
+
+	ok, rehash, err := manager.Verify("secret", "secret")
+	if !errors.Is(err, ErrUnknownAlgorithm) || ok || rehash {
+		t.Fatalf("Verify(plaintext) = %v, %v, %v, want false, false, %v", ok, rehash, err, ErrUnknownAlgorithm)
+	}
+	if manager.IsHashed("secret") {
+		t.Fatal("IsHashed(plaintext) = true")
+	}
+	encoded, err := manager.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !manager.IsHashed(encoded) {
+		t.Fatalf("IsHashed(%q) = false", encoded)
+	}
+	if ok, _, err := manager.Verify("secret", encoded); err != nil || !ok {
+		t.Fatalf("Verify(hashed) = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyInvalidArgon2Hash(t *testing.T) {
+	manager := New(testConfig)
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$bad",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$aGFzaA",
+	} {
+		if ok, _, err := manager.Verify("secret", encoded); ok || err == nil {
+			t.Errorf("Verify(%q) = %v, %v, want an error", encoded, ok, err)
+		}
+	}
+}
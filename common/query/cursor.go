@@ -0,0 +1,213 @@
+package query
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tiebreak 排序键相同时按主键排序, 保证游标分页的顺序稳定
+var tiebreak = Sort{Field: "id", Column: "id", Kind: Int}
+
+// Cursor 游标位置, 保存边界记录的排序键
+type Cursor struct {
+	// Sort 生成游标时的排序, 与当前排序不同时游标无效
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	// Before 为 true 时查询边界记录之前的一页
+	Before bool `json:"b,omitempty"`
+}
+
+// Cursors 游标分页结果, 没有下一页或上一页时对应的游标为空
+type Cursors struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// CursorCodec 使用 HMAC-SHA256 签名游标, 防止客户端篡改排序键
+type CursorCodec struct {
+	key []byte
+}
+
+func NewCursorCodec(key []byte) *CursorCodec {
+	return &CursorCodec{key: key}
+}
+
+func (c *CursorCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Encode 编码并签名游标, 客户端只应把结果当作不透明的字符串
+func (c *CursorCodec) Encode(cursor Cursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + c.sign(payload), nil
+}
+
+// Decode 校验签名并解码游标
+func (c *CursorCodec) Decode(token string) (*Cursor, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+		return nil, invalid("invalid cursor")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, invalid("invalid cursor")
+	}
+	cursor := new(Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, invalid("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// KeysetSort 游标分页使用的排序, 排序中没有 id 时追加 id 作为最后的排序键
+func (q *Query) KeysetSort() []Sort {
+	sorts := slices.Clone(q.Sort)
+	if !slices.ContainsFunc(sorts, func(sort Sort) bool { return sort.Column == tiebreak.Column }) {
+		key := tiebreak
+		if len(sorts) > 0 {
+			key.Desc = sorts[len(sorts)-1].Desc
+		}
+		sorts = append(sorts, key)
+	}
+	return sorts
+}
+
+func sortKey(sorts []Sort) string {
+	keys := make([]string, len(sorts))
+	for index, sort := range sorts {
+		keys[index] = sort.Column
+		if sort.Desc {
+			keys[index] = "-" + sort.Column
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// seekExpression 排在边界记录之后(或之前)的条件, 展开为
+//
+//	(a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?)
+func seekExpression(sorts []Sort, values []any, before bool) clause.Expression {
+	branches := make([]clause.Expression, 0, len(sorts))
+	for index, sort := range sorts {
+		exprs := make([]clause.Expression, 0, index+1)
+		for previous := range index {
+			exprs = append(exprs, clause.Eq{Column: clause.Column{Name: sorts[previous].Column}, Value: values[previous]})
+		}
+		column := clause.Column{Name: sort.Column}
+		if sort.Desc == before {
+			exprs = append(exprs, clause.Gt{Column: column, Value: values[index]})
+		} else {
+			exprs = append(exprs, clause.Lt{Column: column, Value: values[index]})
+		}
+		branches = append(branches, clause.And(exprs...))
+	}
+	return clause.Or(branches...)
+}
+
+func formatValue(value any) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// Seek 游标分页查询 T, 排序列不能包含 NULL
+//
+// db 需要已经绑定连接或事务, 游标无效或与当前排序不一致时返回 ErrInvalidQuery
+func Seek[T any](db *gorm.DB, q *Query, codec *CursorCodec) ([]*T, Cursors, error) {
+	var cursors Cursors
+	sorts := q.KeysetSort()
+	key := sortKey(sorts)
+
+	var cursor *Cursor
+	tx := db.Model(new(T))
+	if !q.Filter.Empty() {
+		tx = tx.Where(q.Filter.expression())
+	}
+	if q.Cursor != "" {
+		var err error
+		if cursor, err = codec.Decode(q.Cursor); err != nil {
+			return nil, cursors, err
+		}
+		if cursor.Sort != key || len(cursor.Values) != len(sorts) {
+			return nil, cursors, invalid("cursor does not match sort")
+		}
+		values := make([]any, len(sorts))
+		for index, sort := range sorts {
+			value, err := Field{Kind: sort.Kind}.convert(cursor.Values[index])
+			if err != nil {
+				return nil, cursors, invalid("invalid cursor")
+			}
+			values[index] = value
+		}
+		tx = tx.Where(seekExpression(sorts, values, cursor.Before))
+	}
+	before := cursor != nil && cursor.Before
+	for _, sort := range sorts {
+		// 向前翻页时反向排序, 查询后再恢复顺序
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc != before})
+	}
+
+	var rows []*T
+	if err := tx.Limit(q.Size + 1).Find(&rows).Error; err != nil {
+		return nil, cursors, err
+	}
+	more := len(rows) > q.Size
+	if more {
+		rows = rows[:q.Size]
+	}
+	if before {
+		slices.Reverse(rows)
+	}
+	if len(rows) == 0 {
+		return rows, cursors, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, cursors, err
+	}
+	encode := func(row *T, before bool) (string, error) {
+		boundary := Cursor{Sort: key, Values: make([]string, len(sorts)), Before: before}
+		for index, sort := range sorts {
+			field := stmt.Schema.LookUpField(sort.Column)
+			if field == nil {
+				return "", fmt.Errorf("query: unknown column %q", sort.Column)
+			}
+			value, _ := field.ValueOf(context.Background(), reflect.ValueOf(row))
+			boundary.Values[index] = formatValue(value)
+		}
+		return codec.Encode(boundary)
+	}
+
+	var err error
+	// 向后翻页时还有更多数据才有下一页, 从游标开始时一定有上一页; 向前翻页相反
+	if more || before {
+		if cursors.Next, err = encode(rows[len(rows)-1], false); err != nil {
+			return nil, cursors, err
+		}
+	}
+	if (before && more) || (!before && cursor != nil) {
+		if cursors.Prev, err = encode(rows[0], true); err != nil {
+			return nil, cursors, err
+		}
+	}
+	return rows, cursors, nil
+}
@@ -0,0 +1,175 @@
+package query
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("key"))
+	cursor := Cursor{Sort: "-age,-id", Values: []string{"18", "7"}, Before: true}
+	token, err := codec.Encode(cursor)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	decoded, err := codec.Decode(token)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.Sort != cursor.Sort || strings.Join(decoded.Values, ",") != "18,7" || !decoded.Before {
+		t.Fatalf("Decode = %+v, want %+v", decoded, cursor)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"-age,-id","v":["99","1"]}`))
+	other, _ := NewCursorCodec([]byte("other")).Encode(cursor)
+	tests := map[string]string{
+		"empty":             "",
+		"no signature":      payload,
+		"forged payload":    forged + "." + signature,
+		"forged signature":  payload + "." + strings.Repeat("A", len(signature)),
+		"other key":         other,
+		"truncated":         token[:len(token)-1],
+		"signed non-base64": "!!!." + codec.sign("!!!"),
+		"signed non-json":   "bm90IGpzb24." + codec.sign("bm90IGpzb24"),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := codec.Decode(token); !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("Decode err = %v, want %v", err, ErrInvalidQuery)
+			}
+		})
+	}
+}
+
+func TestKeysetSort(t *testing.T) {
+	tests := map[string]struct {
+		sort string
+		want string
+	}{
+		"default":          {"", "-id"},
+		"append id":        {"age", "age,id"},
+		"id follows desc":  {"name,-age", "name,-age,-id"},
+		"id already there": {"id,age", "id,age"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, err := testSchema.ParseValues(map[string][]string{"sort": {test.sort}, "cursor": {""}})
+			if err != nil {
+				t.Fatalf("ParseValues: %v", err)
+			}
+			if !q.Keyset {
+				t.Fatal("Keyset = false")
+			}
+			if got := sortKey(q.KeysetSort()); got != test.want {
+				t.Fatalf("KeysetSort = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// seek 调用 Seek 并返回生成的SQL
+func seek(t *testing.T, q *Query, codec *CursorCodec) (string, []any, error) {
+	t.Helper()
+	db := newDryRunDB(t)
+	var stmt *gorm.Statement
+	db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		stmt = tx.Statement
+	})
+	_, _, err := Seek[testRow](db, q, codec)
+	if stmt == nil {
+		return "", nil, err
+	}
+	return stmt.SQL.String(), stmt.Vars, err
+}
+
+func TestSeek(t *testing.T) {
+	codec := NewCursorCodec([]byte("key"))
+	encode := func(cursor Cursor) string {
+		token, err := codec.Encode(cursor)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		return token
+	}
+	tests := map[string]struct {
+		sort   string
+		name   string
+		cursor string
+		sql    string
+	}{
+		"first page": {
+			"-age", "", "",
+			"SELECT * FROM `test_rows` ORDER BY `age` DESC,`id` DESC LIMIT ?",
+		},
+		"next page": {
+			"-age", "", encode(Cursor{Sort: "-age,-id", Values: []string{"18", "7"}}),
+			"SELECT * FROM `test_rows` WHERE (`age` < ? OR (`age` = ? AND `id` < ?)) ORDER BY `age` DESC,`id` DESC LIMIT ?",
+		},
+		"previous page": {
+			"name", "", encode(Cursor{Sort: "name,id", Values: []string{"bob", "7"}, Before: true}),
+			"SELECT * FROM `test_rows` WHERE (`name` < ? OR (`name` = ? AND `id` < ?)) ORDER BY `name` DESC,`id` DESC LIMIT ?",
+		},
+		"filter and cursor": {
+			"-age", "alice", encode(Cursor{Sort: "-age,-id", Values: []string{"18", "7"}}),
+			"SELECT * FROM `test_rows` WHERE `name` = ? AND (`age` < ? OR (`age` = ? AND `id` < ?)) ORDER BY `age` DESC,`id` DESC LIMIT ?",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			values := map[string][]string{"sort": {test.sort}, "cursor": {test.cursor}, "size": {"5"}}
+			if test.name != "" {
+				values["filter[name]"] = []string{test.name}
+			}
+			q, err := testSchema.ParseValues(values)
+			if err != nil {
+				t.Fatalf("ParseValues: %v", err)
+			}
+			sql, vars, err := seek(t, q, codec)
+			if err != nil {
+				t.Fatalf("Seek: %v", err)
+			}
+			if sql != test.sql {
+				t.Fatalf("sql = %s\nwant  %s", sql, test.sql)
+			}
+			if limit := vars[len(vars)-1]; limit != 6 {
+				t.Fatalf("limit = %v, want size + 1", limit)
+			}
+		})
+	}
+}
+
+func TestSeekRejects(t *testing.T) {
+	codec := NewCursorCodec([]byte("key"))
+	encode := func(cursor Cursor) string {
+		token, _ := codec.Encode(cursor)
+		return token
+	}
+	foreign, _ := NewCursorCodec([]byte("other")).Encode(Cursor{Sort: "-age,-id", Values: []string{"18", "7"}})
+	tests := map[string]struct {
+		sort   string
+		cursor string
+	}{
+		"tampered":         {"-age", encode(Cursor{Sort: "-age,-id", Values: []string{"18", "7"}}) + "x"},
+		"sort changed":     {"age", encode(Cursor{Sort: "-age,-id", Values: []string{"18", "7"}})},
+		"direction change": {"-age", encode(Cursor{Sort: "age,id", Values: []string{"18", "7"}})},
+		"missing values":   {"-age", encode(Cursor{Sort: "-age,-id", Values: []string{"18"}})},
+		"invalid value":    {"-age", encode(Cursor{Sort: "-age,-id", Values: []string{"x", "7"}})},
+		"other key":        {"-age", foreign},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, err := testSchema.ParseValues(map[string][]string{"sort": {test.sort}, "cursor": {test.cursor}})
+			if err != nil {
+				t.Fatalf("ParseValues: %v", err)
+			}
+			if _, _, err := seek(t, q, codec); !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("Seek err = %v, want %v", err, ErrInvalidQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// brackets 拆分 "[email][like]" 形式的参数名, 格式不正确时返回 false
+func brackets(key string) ([]string, bool) {
+	var segments []string
+	for key != "" {
+		if key[0] != '[' {
+			return nil, false
+		}
+		end := strings.IndexByte(key, ']')
+		if end < 0 {
+			return nil, false
+		}
+		segments = append(segments, key[1:end])
+		key = key[end+1:]
+	}
+	return segments, len(segments) > 0
+}
+
+func isIndex(segment string) bool {
+	_, err := strconv.ParseUint(segment, 10, 32)
+	return err == nil
+}
+
+// splitValues in 和 between 的多个值使用逗号分隔
+func splitValues(op string, values []string) []string {
+	switch Operator(strings.ToLower(op)) {
+	case In, Between:
+		var result []string
+		for _, value := range values {
+			result = append(result, strings.Split(value, ",")...)
+		}
+		return result
+	}
+	return values
+}
+
+// ParseValues 解析 URL 查询参数
+//
+//	filter[email][like]=foo      条件之间为 AND, 省略操作符时为 eq
+//	filter[id][in]=1,2,3         in 和 between 的多个值使用逗号分隔
+//	filter[or][name]=a&filter[or][email][like]=b
+//	                             or 中的条件组成一个 OR 组, 使用 filter[or][0][...] 编号可以写多个 OR 组
+//	sort=-created_at,name        "-" 前缀表示降序
+//	page=1&size=10
+//	cursor=&size=10              使用游标分页, 第一页的 cursor 为空, 之后使用响应中的 next 或 prev
+//
+// 其他参数会被忽略
+func (s *Schema) ParseValues(values url.Values) (*Query, error) {
+	query := new(Query)
+	orGroups := make(map[string]*Group)
+	var labels []string
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		segments, ok := brackets(strings.TrimPrefix(key, "filter"))
+		if !ok {
+			return nil, invalid("malformed parameter %q", key)
+		}
+		target := &query.Filter
+		if segments[0] == "or" {
+			label := ""
+			if segments = segments[1:]; len(segments) > 0 && isIndex(segments[0]) {
+				label, segments = segments[0], segments[1:]
+			}
+			if orGroups[label] == nil {
+				orGroups[label] = &Group{Or: true}
+				labels = append(labels, label)
+			}
+			target = orGroups[label]
+		}
+		if len(segments) == 0 || len(segments) > 2 {
+			return nil, invalid("malformed parameter %q", key)
+		}
+		op := ""
+		if len(segments) == 2 {
+			op = segments[1]
+		}
+		for _, value := range values[key] {
+			condition, err := s.condition(segments[0], op, splitValues(op, []string{value}))
+			if err != nil {
+				return nil, err
+			}
+			target.Conditions = append(target.Conditions, condition)
+		}
+	}
+	for _, label := range labels {
+		query.Filter.Groups = append(query.Filter.Groups, *orGroups[label])
+	}
+
+	var sortFields []string
+	if value := values.Get("sort"); value != "" {
+		sortFields = strings.Split(value, ",")
+	}
+	page, err := intValue(values.Get("page"))
+	if err != nil {
+		return nil, invalid("page must be a number")
+	}
+	size, err := intValue(values.Get("size"))
+	if err != nil {
+		return nil, invalid("size must be a number")
+	}
+	if values.Has("cursor") {
+		query.Keyset, query.Cursor = true, values.Get("cursor")
+	}
+	return s.finish(query, sortFields, page, size)
+}
+
+func intValue(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// ParseArgs 解析 GraphQL 参数
+//
+//	filter: [{field: "email", op: "like", value: "foo"}]   条件之间为 AND
+//	or:     [{field: "name", value: "a"}, {field: "email", op: "in", values: ["b", "c"]}]
+//	sort:   ["-created_at"]
+//	page: 1, size: 10
+//	cursor: ""                   使用游标分页
+func (s *Schema) ParseArgs(args map[string]any) (*Query, error) {
+	query := new(Query)
+	conditions, err := s.argConditions(args["filter"])
+	if err != nil {
+		return nil, err
+	}
+	query.Filter.Conditions = conditions
+	if conditions, err = s.argConditions(args["or"]); err != nil {
+		return nil, err
+	}
+	if len(conditions) > 0 {
+		query.Filter.Groups = append(query.Filter.Groups, Group{Or: true, Conditions: conditions})
+	}
+
+	var sortFields []string
+	switch sortArg := args["sort"].(type) {
+	case string:
+		sortFields = strings.Split(sortArg, ",")
+	case []any:
+		for _, field := range sortArg {
+			sortFields = append(sortFields, fmt.Sprint(field))
+		}
+	}
+	page, _ := args["page"].(int)
+	size, _ := args["size"].(int)
+	if cursor, ok := args["cursor"].(string); ok {
+		query.Keyset, query.Cursor = true, cursor
+	}
+	return s.finish(query, sortFields, page, size)
+}
+
+func (s *Schema) argConditions(arg any) ([]Condition, error) {
+	items, _ := arg.([]any)
+	conditions := make([]Condition, 0, len(items))
+	for _, item := range items {
+		input, ok := item.(map[string]any)
+		if !ok {
+			return nil, invalid("malformed filter")
+		}
+		field, _ := input["field"].(string)
+		op, _ := input["op"].(string)
+		var raw []string
+		if values, ok := input["values"].([]any); ok {
+			for _, value := range values {
+				raw = append(raw, fmt.Sprint(value))
+			}
+		} else if value, ok := input["value"]; ok && value != nil {
+			raw = splitValues(op, []string{fmt.Sprint(value)})
+		}
+		condition, err := s.condition(field, op, raw)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+func (s *Schema) finish(query *Query, sortFields []string, page, size int) (*Query, error) {
+	if countConditions(query.Filter) > maxConditions {
+		return nil, invalid("too many conditions, at most %d", maxConditions)
+	}
+	sorts, err := s.sort(sortFields)
+	if err != nil {
+		return nil, err
+	}
+	query.Sort = sorts
+	if err := s.paginate(query, page, size); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
@@ -0,0 +1,276 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operator 过滤操作符
+type Operator string
+
+const (
+	Eq      Operator = "eq"
+	Ne      Operator = "ne"
+	Lt      Operator = "lt"
+	Gt      Operator = "gt"
+	In      Operator = "in"
+	Like    Operator = "like"
+	Between Operator = "between"
+	IsNull  Operator = "isnull"
+)
+
+// Kind 字段的值类型, 查询参数按类型转换后才会进入SQL
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Float
+	Bool
+	Time
+)
+
+const (
+	defaultPageSize = 10
+	defaultMaxSize  = 20
+	// maxConditions 单次查询最多的过滤条件数
+	maxConditions = 20
+	// maxValues in 操作符最多的值个数
+	maxValues = 100
+)
+
+// ErrInvalidQuery 查询参数不合法, 具体原因包装在错误信息中
+var ErrInvalidQuery = errors.New("query: invalid query")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidQuery, fmt.Sprintf(format, args...))
+}
+
+// Field 允许查询的字段
+type Field struct {
+	// Column 数据库列名, 只有白名单中的列名会出现在SQL中
+	Column string
+	Kind   Kind
+	// Operators 允许的操作符, 为空时允许全部操作符
+	Operators []Operator
+	Sortable  bool
+}
+
+func (f Field) allow(op Operator) bool {
+	if len(f.Operators) == 0 {
+		return op != Like || f.Kind == String
+	}
+	return slices.Contains(f.Operators, op)
+}
+
+// convert 按字段类型转换查询参数
+func (f Field) convert(raw string) (any, error) {
+	switch f.Kind {
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Float:
+		return strconv.ParseFloat(raw, 64)
+	case Bool:
+		return strconv.ParseBool(raw)
+	case Time:
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	}
+	return raw, nil
+}
+
+// Condition 单个过滤条件
+type Condition struct {
+	Field  string
+	Column string
+	Op     Operator
+	Values []any
+}
+
+// Group 过滤条件组, Or 为 false 时组内条件为 AND
+type Group struct {
+	Or         bool
+	Conditions []Condition
+	Groups     []Group
+}
+
+// Empty 组内是否没有任何条件
+func (g Group) Empty() bool {
+	return len(g.Conditions) == 0 && len(g.Groups) == 0
+}
+
+// Sort 排序字段
+type Sort struct {
+	Field  string
+	Column string
+	Kind   Kind
+	Desc   bool
+}
+
+// Query 解析并校验后的查询
+type Query struct {
+	Filter Group
+	Sort   []Sort
+	Page   int
+	Size   int
+	// Keyset 使用游标分页, 此时忽略 Page
+	Keyset bool
+	// Cursor 未解码的游标, 为空时从第一页开始
+	Cursor string
+}
+
+// Offset 分页偏移量
+func (q *Query) Offset() int {
+	return (q.Page - 1) * q.Size
+}
+
+// Schema 模型允许查询的字段白名单
+type Schema struct {
+	fields      map[string]Field
+	defaultSort []string
+	maxSize     int
+}
+
+type SchemaOption func(*Schema)
+
+// WithField 允许按 name 查询, name 为查询参数中使用的字段名
+func WithField(name string, field Field) SchemaOption {
+	return func(s *Schema) {
+		s.fields[name] = field
+	}
+}
+
+// WithDefaultSort 未指定 sort 时使用的排序, 格式与 sort 参数相同
+func WithDefaultSort(sort ...string) SchemaOption {
+	return func(s *Schema) {
+		s.defaultSort = sort
+	}
+}
+
+// WithMaxSize 每页最多的记录数
+func WithMaxSize(size int) SchemaOption {
+	return func(s *Schema) {
+		s.maxSize = size
+	}
+}
+
+func NewSchema(opts ...SchemaOption) *Schema {
+	schema := &Schema{fields: make(map[string]Field), maxSize: defaultMaxSize}
+	for _, opt := range opts {
+		opt(schema)
+	}
+	return schema
+}
+
+// Field 按查询参数中的字段名查找字段
+func (s *Schema) Field(name string) (Field, bool) {
+	field, ok := s.fields[name]
+	return field, ok
+}
+
+// condition 校验字段和操作符并转换参数, op 为空时为 eq
+func (s *Schema) condition(name, op string, raw []string) (Condition, error) {
+	field, ok := s.fields[name]
+	if !ok {
+		return Condition{}, invalid("unknown field %q", name)
+	}
+	operator := Operator(strings.ToLower(op))
+	if operator == "" {
+		operator = Eq
+	}
+	if !field.allow(operator) {
+		return Condition{}, invalid("operator %q is not allowed on %q", op, name)
+	}
+
+	condition := Condition{Field: name, Column: field.Column, Op: operator}
+	switch operator {
+	case Eq, Ne, Lt, Gt, Like:
+		if len(raw) != 1 {
+			return Condition{}, invalid("%q expects one value", operator)
+		}
+	case In:
+		if len(raw) == 0 || len(raw) > maxValues {
+			return Condition{}, invalid("%q expects 1 to %d values", operator, maxValues)
+		}
+	case Between:
+		if len(raw) != 2 {
+			return Condition{}, invalid("%q expects two values", operator)
+		}
+	case IsNull:
+		isNull := true
+		if len(raw) > 0 && raw[0] != "" {
+			value, err := strconv.ParseBool(raw[0])
+			if err != nil {
+				return Condition{}, invalid("%q expects true or false", operator)
+			}
+			isNull = value
+		}
+		condition.Values = []any{isNull}
+		return condition, nil
+	default:
+		return Condition{}, invalid("unknown operator %q", op)
+	}
+
+	for _, value := range raw {
+		converted, err := field.convert(value)
+		if err != nil {
+			return Condition{}, invalid("invalid value %q for %q", value, name)
+		}
+		condition.Values = append(condition.Values, converted)
+	}
+	return condition, nil
+}
+
+// sort 解析排序字段, "-" 前缀表示降序
+func (s *Schema) sort(fields []string) ([]Sort, error) {
+	if len(fields) == 0 {
+		fields = s.defaultSort
+	}
+	sorts := make([]Sort, 0, len(fields))
+	for _, name := range fields {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimLeft(name, "+-")
+		field, ok := s.fields[name]
+		if !ok || !field.Sortable {
+			return nil, invalid("cannot sort by %q", name)
+		}
+		sorts = append(sorts, Sort{Field: name, Column: field.Column, Kind: field.Kind, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// paginate 校验分页参数, 未指定时使用第一页和默认大小
+func (s *Schema) paginate(query *Query, page, size int) error {
+	if page < 0 || size < 0 {
+		return invalid("page and size must be positive")
+	}
+	if page == 0 {
+		page = 1
+	}
+	if size == 0 {
+		size = min(defaultPageSize, s.maxSize)
+	}
+	if size > s.maxSize {
+		return invalid("size must not exceed %d", s.maxSize)
+	}
+	query.Page, query.Size = page, size
+	return nil
+}
+
+func countConditions(group Group) int {
+	count := len(group.Conditions)
+	for _, child := range group.Groups {
+		count += countConditions(child)
+	}
+	return count
+}
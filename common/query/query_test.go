@@ -0,0 +1,198 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type testRow struct {
+	ID        uint
+	Name      string
+	Email     string
+	Age       int
+	CreatedAt time.Time
+}
+
+var testSchema = NewSchema(
+	WithField("id", Field{Column: "id", Kind: Int, Sortable: true}),
+	WithField("name", Field{Column: "name", Sortable: true}),
+	WithField("email", Field{Column: "email", Operators: []Operator{Eq, Like}}),
+	WithField("age", Field{Column: "age", Kind: Int, Sortable: true}),
+	WithField("created_at", Field{Column: "created_at", Kind: Time, Sortable: true}),
+	WithDefaultSort("-id"),
+	WithMaxSize(50),
+)
+
+// newDryRunDB 只生成SQL, 不连接数据库
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return db
+}
+
+// toSQL 生成 q 过滤和排序的SQL
+func toSQL(t *testing.T, q *Query) (string, []any) {
+	t.Helper()
+	stmt := newDryRunDB(t).Scopes(q.Scope).Find(&[]testRow{}).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestParseValues(t *testing.T) {
+	tests := map[string]struct {
+		query string
+		sql   string
+		vars  []any
+	}{
+		"default sort": {
+			"",
+			"SELECT * FROM `test_rows` ORDER BY `id` DESC",
+			nil,
+		},
+		"implicit eq": {
+			"filter[name]=alice",
+			"SELECT * FROM `test_rows` WHERE `name` = ? ORDER BY `id` DESC",
+			[]any{"alice"},
+		},
+		"conditions are AND": {
+			"filter[age][gt]=18&filter[age][lt]=30",
+			"SELECT * FROM `test_rows` WHERE `age` > ? AND `age` < ? ORDER BY `id` DESC",
+			[]any{int64(18), int64(30)},
+		},
+		"in": {
+			"filter[id][in]=1,2,3",
+			"SELECT * FROM `test_rows` WHERE `id` IN (?,?,?) ORDER BY `id` DESC",
+			[]any{int64(1), int64(2), int64(3)},
+		},
+		"between": {
+			"filter[created_at][between]=2024-01-01,2024-02-01T00:00:00Z",
+			"SELECT * FROM `test_rows` WHERE `created_at` BETWEEN ? AND ? ORDER BY `id` DESC",
+			[]any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		"like escapes wildcards": {
+			"filter[email][like]=a%25_b",
+			"SELECT * FROM `test_rows` WHERE `email` LIKE ? ORDER BY `id` DESC",
+			[]any{`%a\%\_b%`},
+		},
+		"isnull": {
+			"filter[name][isnull]=false",
+			"SELECT * FROM `test_rows` WHERE `name` IS NOT NULL ORDER BY `id` DESC",
+			nil,
+		},
+		"or group": {
+			"filter[age]=1&filter[or][name]=a&filter[or][email][like]=b",
+			"SELECT * FROM `test_rows` WHERE `age` = ? AND (`email` LIKE ? OR `name` = ?) ORDER BY `id` DESC",
+			[]any{int64(1), "%b%", "a"},
+		},
+		"numbered or groups": {
+			"filter[or][0][name]=a&filter[or][0][age]=1&filter[or][1][name]=b&filter[or][1][age]=2",
+			"SELECT * FROM `test_rows` WHERE (`age` = ? OR `name` = ?) AND (`age` = ? OR `name` = ?) ORDER BY `id` DESC",
+			[]any{int64(1), "a", int64(2), "b"},
+		},
+		"sort": {
+			"sort=-created_at,name",
+			"SELECT * FROM `test_rows` ORDER BY `created_at` DESC,`name`",
+			nil,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatalf("ParseQuery: %v", err)
+			}
+			q, err := testSchema.ParseValues(values)
+			if err != nil {
+				t.Fatalf("ParseValues: %v", err)
+			}
+			sql, vars := toSQL(t, q)
+			if sql != test.sql {
+				t.Fatalf("sql = %s\nwant  %s", sql, test.sql)
+			}
+			if len(vars) != 0 || len(test.vars) != 0 {
+				if !reflect.DeepEqual(vars, test.vars) {
+					t.Fatalf("vars = %#v, want %#v", vars, test.vars)
+				}
+			}
+		})
+	}
+}
+
+func TestParseValuesRejects(t *testing.T) {
+	tooMany := url.Values{}
+	for range maxConditions + 1 {
+		tooMany.Add("filter[name]", "a")
+	}
+	tests := map[string]url.Values{
+		"unknown field":        {"filter[password]": {"x"}},
+		"column name as field": {"filter[country_code]": {"x"}},
+		"injection in field":   {"filter[name`) OR 1=1 --]": {"x"}},
+		"operator not allowed": {"filter[email][gt]": {"x"}},
+		"like on number":       {"filter[age][like]": {"1"}},
+		"unknown operator":     {"filter[name][regexp]": {"x"}},
+		"malformed brackets":   {"filter[name": {"x"}},
+		"too many segments":    {"filter[name][eq][x]": {"x"}},
+		"invalid number":       {"filter[age]": {"one"}},
+		"invalid time":         {"filter[created_at]": {"yesterday"}},
+		"between one value":    {"filter[age][between]": {"1"}},
+		"invalid isnull":       {"filter[name][isnull]": {"maybe"}},
+		"sort unknown field":   {"sort": {"password"}},
+		"sort not sortable":    {"sort": {"email"}},
+		"negative page":        {"page": {"-1"}},
+		"page not a number":    {"page": {"x"}},
+		"size too large":       {"size": {"51"}},
+		"too many conditions":  tooMany,
+		"empty or group":       {"filter[or]": {"x"}},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := testSchema.ParseValues(values); !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("ParseValues(%v) err = %v, want %v", values, err, ErrInvalidQuery)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	q, err := testSchema.ParseArgs(map[string]any{
+		"filter": []any{
+			map[string]any{"field": "age", "op": "in", "values": []any{1, 2}},
+		},
+		"or": []any{
+			map[string]any{"field": "name", "value": "a"},
+			map[string]any{"field": "email", "op": "like", "value": "b"},
+		},
+		"sort": []any{"-age"},
+		"page": 2,
+		"size": 5,
+	})
+	if err != nil {
+		t.Fatalf("ParseArgs: %v", err)
+	}
+	sql, vars := toSQL(t, q)
+	want := "SELECT * FROM `test_rows` WHERE `age` IN (?,?) AND (`name` = ? OR `email` LIKE ?) ORDER BY `age` DESC"
+	if sql != want {
+		t.Fatalf("sql = %s\nwant  %s", sql, want)
+	}
+	if wantVars := []any{int64(1), int64(2), "a", "%b%"}; !reflect.DeepEqual(vars, wantVars) {
+		t.Fatalf("vars = %#v, want %#v", vars, wantVars)
+	}
+	if q.Page != 2 || q.Size != 5 || q.Offset() != 5 {
+		t.Fatalf("page = %d, size = %d, offset = %d", q.Page, q.Size, q.Offset())
+	}
+
+	if _, err := testSchema.ParseArgs(map[string]any{"filter": []any{map[string]any{"field": "password", "value": "x"}}}); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("unknown field: err = %v, want %v", err, ErrInvalidQuery)
+	}
+	if _, err := testSchema.ParseArgs(map[string]any{"filter": []any{"name"}}); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("malformed filter: err = %v, want %v", err, ErrInvalidQuery)
+	}
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (c Condition) expression() clause.Expression {
+	column := clause.Column{Name: c.Column}
+	switch c.Op {
+	case Ne:
+		return clause.Neq{Column: column, Value: c.Values[0]}
+	case Lt:
+		return clause.Lt{Column: column, Value: c.Values[0]}
+	case Gt:
+		return clause.Gt{Column: column, Value: c.Values[0]}
+	case In:
+		return clause.IN{Column: column, Values: c.Values}
+	case Like:
+		// 参数中的通配符按普通字符匹配
+		return clause.Like{Column: column, Value: "%" + likeEscaper.Replace(c.Values[0].(string)) + "%"}
+	case Between:
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, c.Values[0], c.Values[1]}}
+	case IsNull:
+		if c.Values[0].(bool) {
+			return clause.Eq{Column: column, Value: nil}
+		}
+		return clause.Neq{Column: column, Value: nil}
+	}
+	return clause.Eq{Column: column, Value: c.Values[0]}
+}
+
+func (g Group) expression() clause.Expression {
+	exprs := make([]clause.Expression, 0, len(g.Conditions)+len(g.Groups))
+	for _, condition := range g.Conditions {
+		exprs = append(exprs, condition.expression())
+	}
+	for _, group := range g.Groups {
+		if !group.Empty() {
+			exprs = append(exprs, group.expression())
+		}
+	}
+	if g.Or {
+		return clause.Or(exprs...)
+	}
+	return clause.And(exprs...)
+}
+
+// Scope 添加过滤和排序条件, 不包含分页
+//
+//	db.Model(&model.UserModel{}).Scopes(q.Scope).Count(&total)
+func (q *Query) Scope(db *gorm.DB) *gorm.DB {
+	if !q.Filter.Empty() {
+		db = db.Where(q.Filter.expression())
+	}
+	for _, sort := range q.Sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+	}
+	return db
+}
+
+// Paginate 添加分页条件
+func (q *Query) Paginate(db *gorm.DB) *gorm.DB {
+	return db.Offset(q.Offset()).Limit(q.Size)
+}
@@ -1,5 +1,10 @@
 package utils
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
 // BoolToError converts a boolean condition to an error
 // If the condition is true, returns nil
 // If the condition is false, returns the provided error
@@ -9,3 +14,12 @@ func BoolToError(condition bool, err error) error {
     }
     return err
 }
+
+// NewGID 生成全局唯一ID, 32位十六进制字符串
+func NewGID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
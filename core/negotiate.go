@@ -0,0 +1,287 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
+
+// Encoder 响应编码器
+//
+// 除 JSON 外, 响应体会先转换为与 JSON 相同结构的 map/slice 再交给 Marshal,
+// 保证所有格式的字段名和错误结构一致
+type Encoder struct {
+	// Format ?format= 参数的值
+	Format string
+	// MediaTypes 可以匹配 Accept 的类型, 第一个作为 Content-Type
+	MediaTypes []string
+	Marshal    func(v any) ([]byte, error)
+}
+
+const defaultFormat = "json"
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = make(map[string]Encoder)
+	// formats 按注册顺序保存, Accept 的权重相同时优先使用先注册的格式
+	formats []string
+)
+
+func init() {
+	RegisterEncoder(Encoder{Format: "json", MediaTypes: []string{"application/json", problemMediaType}, Marshal: json.Marshal})
+	RegisterEncoder(Encoder{Format: "xml", MediaTypes: []string{"application/xml", "text/xml"}, Marshal: marshalXML})
+	RegisterEncoder(Encoder{Format: "yaml", MediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"}, Marshal: yaml.Marshal})
+	RegisterEncoder(Encoder{Format: "msgpack", MediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}, Marshal: marshalMsgPack})
+	RegisterEncoder(Encoder{Format: "protobuf", MediaTypes: []string{"application/x-protobuf", "application/protobuf"}, Marshal: marshalProtobuf})
+}
+
+// RegisterEncoder 注册或替换响应编码器
+func RegisterEncoder(encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	if _, ok := encoders[encoder.Format]; !ok {
+		formats = append(formats, encoder.Format)
+	}
+	encoders[encoder.Format] = encoder
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept 解析 Accept 中的类型, 保留 q=0 用于排除格式
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// specificity 类型匹配的精确程度, 不匹配时为 -1
+func specificity(pattern, mediaType string) int {
+	switch {
+	case pattern == mediaType:
+		return 2
+	case pattern == "*/*":
+		return 0
+	}
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	if ok && strings.HasPrefix(mediaType, prefix+"/") {
+		return 1
+	}
+	return -1
+}
+
+// encoderQuality 按最精确匹配的类型取权重, explicit 表示由非通配的类型匹配
+func encoderQuality(ranges []acceptRange, encoder Encoder) (quality float64, explicit bool) {
+	for _, mediaType := range encoder.MediaTypes {
+		best, bestQuality := -1, 0.0
+		for _, accepted := range ranges {
+			if level := specificity(accepted.mediaType, mediaType); level > best {
+				best, bestQuality = level, accepted.quality
+			}
+		}
+		if best >= 0 && bestQuality > quality {
+			quality, explicit = bestQuality, best == 2
+		}
+	}
+	return quality, explicit
+}
+
+// negotiate ?format= 优先于 Accept, 都没有匹配的格式时使用 JSON
+//
+// Accept 接受 JSON 时, 只有其他格式由非通配的类型匹配、权重最高且是客户端最优先的类型才使用该格式,
+// 因此浏览器的 text/html,application/xml;q=0.9,*/*;q=0.8 仍然返回 JSON
+func negotiate(ctx *gin.Context) Encoder {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	if format := strings.ToLower(ctx.Query("format")); format != "" {
+		if encoder, ok := encoders[format]; ok {
+			return encoder
+		}
+	}
+	ranges := parseAccept(ctx.GetHeader("Accept"))
+	top := 0.0
+	for _, accepted := range ranges {
+		top = max(top, accepted.quality)
+	}
+	fallback := encoders[defaultFormat]
+	jsonQuality, _ := encoderQuality(ranges, fallback)
+
+	var best Encoder
+	bestQuality, bestExplicit := 0.0, false
+	for _, format := range formats {
+		encoder := encoders[format]
+		if quality, explicit := encoderQuality(ranges, encoder); quality > bestQuality {
+			best, bestQuality, bestExplicit = encoder, quality, explicit
+		}
+	}
+	if bestQuality == 0 || best.Format == defaultFormat {
+		return fallback
+	}
+	if jsonQuality > 0 && (!bestExplicit || bestQuality < top) {
+		return fallback
+	}
+	return best
+}
+
+// normalize 将响应体转换为与 JSON 结构相同的值, 整数保持为 int64
+func normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertNumbers(value), nil
+}
+
+func convertNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []any:
+		for index, item := range v {
+			v[index] = convertNumbers(item)
+		}
+	case json.Number:
+		if number, err := v.Int64(); err == nil {
+			return number
+		}
+		number, _ := v.Float64()
+		return number
+	}
+	return value
+}
+
+// render 按协商的格式输出响应, 编码失败时退回 JSON
+func render(ctx *gin.Context, status int, body any) {
+	ctx.Header("Vary", "Accept")
+	encoder := negotiate(ctx)
+	if encoder.Format == defaultFormat {
+		ctx.JSON(status, body)
+		return
+	}
+	value, err := normalize(body)
+	if err == nil {
+		var data []byte
+		if data, err = encoder.Marshal(value); err == nil {
+			ctx.Data(status, encoder.MediaTypes[0], data)
+			return
+		}
+	}
+	_ = ctx.Error(err)
+	ctx.JSON(status, body)
+}
+
+// marshalXML 根元素为 response, 数组元素使用 item
+func marshalXML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	if err := writeXML(encoder, "response", v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xmlName 将 map 的键转换为合法的元素名
+func xmlName(key string) string {
+	name := []rune(key)
+	for index, char := range name {
+		valid := char == '_' || char == '-' || char == '.' || ('a' <= char && char <= 'z') ||
+			('A' <= char && char <= 'Z') || ('0' <= char && char <= '9')
+		if !valid || index == 0 && (char == '-' || char == '.' || ('0' <= char && char <= '9')) {
+			name[index] = '_'
+		}
+	}
+	if len(name) == 0 {
+		return "_"
+	}
+	return string(name)
+}
+
+func writeXML(encoder *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeXML(encoder, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXML(encoder, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func marshalMsgPack(v any) ([]byte, error) {
+	var data []byte
+	handle := new(codec.MsgpackHandle)
+	handle.WriteExt = true
+	err := codec.NewEncoderBytes(&data, handle).Encode(v)
+	return data, err
+}
+
+// marshalProtobuf 响应编码为 google.protobuf.Struct, 客户端不需要额外的 .proto 文件
+func marshalProtobuf(v any) ([]byte, error) {
+	value, err := structpb.NewValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if object := value.GetStructValue(); object != nil {
+		return proto.Marshal(object)
+	}
+	return proto.Marshal(value)
+}
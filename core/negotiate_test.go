@@ -0,0 +1,46 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]struct {
+		target string
+		accept string
+		want   string
+	}{
+		"no accept":            {"/", "", "json"},
+		"browser":              {"/", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,*/*;q=0.8", "json"},
+		"wildcard":             {"/", "*/*", "json"},
+		"type wildcard":        {"/", "text/*, */*;q=0.1", "json"},
+		"only type wildcard":   {"/", "text/*", "xml"},
+		"q tie":                {"/", "application/xml, application/json", "json"},
+		"explicit xml":         {"/", "application/xml", "xml"},
+		"xml preferred":        {"/", "application/xml, application/json;q=0.9", "xml"},
+		"yaml over wildcard":   {"/", "application/yaml, */*;q=0.1", "yaml"},
+		"json preferred":       {"/", "application/json, application/xml;q=0.9", "json"},
+		"json excluded":        {"/", "text/html, application/xml;q=0.5, application/json;q=0", "xml"},
+		"xml excluded":         {"/", "application/xml;q=0, */*", "json"},
+		"unsupported fallback": {"/", "text/csv", "json"},
+		"msgpack":              {"/", "application/msgpack", "msgpack"},
+		"format query":         {"/?format=yaml", "application/xml", "yaml"},
+		"unknown format query": {"/?format=csv", "application/xml", "xml"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.accept != "" {
+				ctx.Request.Header.Set("Accept", test.accept)
+			}
+			if got := negotiate(ctx).Format; got != test.want {
+				t.Fatalf("negotiate(%q) = %s, want %s", test.accept, got, test.want)
+			}
+		})
+	}
+}
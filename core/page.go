@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"template/common/query"
+	"template/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page 列表接口统一的分页数据
+//
+// 页码分页时返回 total 和 page, 游标分页时返回 next 和 prev
+type Page struct {
+	Items   any    `json:"items"`
+	Total   *int64 `json:"total,omitempty"`
+	Page    int    `json:"page,omitempty"`
+	Size    int    `json:"size"`
+	HasMore bool   `json:"has_more"`
+	Next    string `json:"next,omitempty"`
+	Prev    string `json:"prev,omitempty"`
+
+	links []link
+}
+
+type link struct {
+	rel    string
+	values map[string]string
+}
+
+type PageOption func(*Page)
+
+// WithOffset 页码分页
+func WithOffset(total int64, page, size int) PageOption {
+	return func(p *Page) {
+		p.Total, p.Page, p.Size = &total, page, size
+		p.HasMore = int64(page)*int64(size) < total
+
+		pageLink := func(rel string, page int) link {
+			return link{rel: rel, values: map[string]string{"page": strconv.Itoa(page), "size": strconv.Itoa(size)}}
+		}
+		last := 1
+		if size > 0 {
+			last = max(int((total+int64(size)-1)/int64(size)), 1)
+		}
+		p.links = append(p.links, pageLink("first", 1))
+		if page > 1 {
+			p.links = append(p.links, pageLink("prev", min(page-1, last)))
+		}
+		if p.HasMore {
+			p.links = append(p.links, pageLink("next", page+1))
+		}
+		p.links = append(p.links, pageLink("last", last))
+	}
+}
+
+// WithPagination 使用 dto.Pagination 的页码分页
+func WithPagination(pagination dto.Pagination, total int64) PageOption {
+	return WithOffset(total, pagination.Page, pagination.Size)
+}
+
+// WithCursors 游标分页, 有下一页游标时 has_more 为 true
+func WithCursors(size int, cursors query.Cursors) PageOption {
+	return func(p *Page) {
+		p.Size, p.Next, p.Prev = size, cursors.Next, cursors.Prev
+		p.HasMore = cursors.Next != ""
+
+		sizeValue := strconv.Itoa(size)
+		if cursors.Next != "" {
+			p.links = append(p.links, link{rel: "next", values: map[string]string{"cursor": cursors.Next, "size": sizeValue}})
+		}
+		if cursors.Prev != "" {
+			p.links = append(p.links, link{rel: "prev", values: map[string]string{"cursor": cursors.Prev, "size": sizeValue}})
+		}
+		p.links = append(p.links, link{rel: "first", values: map[string]string{"cursor": "", "size": sizeValue}})
+	}
+}
+
+// WithQuery 按 q 的分页方式生成分页数据, 游标分页时忽略 total
+func WithQuery(q *query.Query, total int64, cursors query.Cursors) PageOption {
+	if q.Keyset {
+		return WithCursors(q.Size, cursors)
+	}
+	return WithOffset(total, q.Page, q.Size)
+}
+
+func NewPage(items any, opts ...PageOption) *Page {
+	// 空列表输出 [] 而不是 null
+	if value := reflect.ValueOf(items); items == nil || value.Kind() == reflect.Slice && value.IsNil() {
+		items = []any{}
+	}
+	page := &Page{Items: items}
+	for _, opt := range opts {
+		opt(page)
+	}
+	return page
+}
+
+// Link RFC 5988 Link 头, 在当前请求地址上替换分页参数
+func (p *Page) Link(requestURL *url.URL) string {
+	parts := make([]string, 0, len(p.links))
+	for _, link := range p.links {
+		target := *requestURL
+		values := target.Query()
+		for key, value := range link.values {
+			values.Set(key, value)
+		}
+		if _, ok := link.values["cursor"]; ok {
+			values.Del("page")
+		}
+		target.RawQuery = values.Encode()
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, target.RequestURI(), link.rel))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ResponsePage 返回分页列表, 同时设置 Link 头
+//
+//	core.ResponsePage(ctx, items, core.WithPagination(pagination, total))
+//	core.ResponsePage(ctx, items, core.WithCursors(size, cursors))
+func ResponsePage(ctx *gin.Context, items any, opts ...PageOption) {
+	page := NewPage(items, opts...)
+	if link := page.Link(ctx.Request.URL); link != "" {
+		ctx.Header("Link", link)
+	}
+	render(ctx, http.StatusOK, gin.H{
+		"code":    0,
+		"message": successMessage(ctx),
+		"data":    page,
+	})
+}
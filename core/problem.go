@@ -0,0 +1,112 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"template/global/config"
+	"template/i18n"
+	builtinerrors "template/internal/builtinErrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	problemMediaType = "application/problem+json"
+	defaultTypeBase  = "/errors"
+
+	problemContextKey = "core.problem"
+)
+
+// problemCategories 错误码区间对应的 type 路径, 与 internal/builtin 中的错误码区间一致
+var problemCategories = map[int]string{
+	1: "system",
+	2: "auth",
+	3: "database",
+}
+
+// Problem RFC 7807 Problem Details, code 和 errors 为扩展成员
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     int               `json:"code"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// ProblemDetails 将 Problem Details 的配置写入上下文, 没有经过该中间件的请求只在 Accept 要求时使用
+func ProblemDetails(conf config.ProblemConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(problemContextKey, conf)
+		ctx.Next()
+	}
+}
+
+func problemConfig(ctx *gin.Context) config.ProblemConfig {
+	value, _ := ctx.Get(problemContextKey)
+	conf, _ := value.(config.ProblemConfig)
+	return conf
+}
+
+// problemType 按错误码区间生成 type, 如 2002 为 /errors/auth/2002
+func problemType(ctx *gin.Context, code int) string {
+	base := defaultTypeBase
+	if typeBase := problemConfig(ctx).TypeBase; typeBase != "" {
+		base = typeBase
+	}
+	category, ok := problemCategories[code/1000]
+	if !ok {
+		category = "other"
+	}
+	return fmt.Sprintf("%s/%s/%d", strings.TrimSuffix(base, "/"), category, code)
+}
+
+// NewProblem 将错误转换为 Problem Details, title 为未填充参数的错误信息, detail 为本次的错误信息, 都使用错误的语言
+func NewProblem(ctx *gin.Context, err *builtinerrors.Exception) *Problem {
+	problem := &Problem{
+		Type:     problemType(ctx, err.Code()),
+		Title:    i18n.T(err.Locale(), err.Path()),
+		Status:   err.Status(),
+		Detail:   err.Error(),
+		Instance: ctx.Request.URL.Path,
+		Code:     err.Code(),
+	}
+	if problem.Detail == problem.Title {
+		problem.Detail = ""
+	}
+	if fields := err.ErrorMessage(); len(fields) > 0 {
+		problem.Errors = fields
+	}
+	return problem
+}
+
+// wantsProblem 配置开启或请求的 Accept 包含 application/problem+json 时使用 Problem Details
+func wantsProblem(ctx *gin.Context) bool {
+	if problemConfig(ctx).Enable {
+		return true
+	}
+	for _, accepted := range parseAccept(ctx.GetHeader("Accept")) {
+		if accepted.mediaType == problemMediaType && accepted.quality > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// renderProblem JSON 格式使用 application/problem+json, 其他格式按协商结果输出相同的成员
+func renderProblem(ctx *gin.Context, err *builtinerrors.Exception) {
+	problem := NewProblem(ctx, err)
+	if negotiate(ctx).Format != defaultFormat {
+		render(ctx, problem.Status, problem)
+		return
+	}
+	data, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		render(ctx, problem.Status, problem)
+		return
+	}
+	ctx.Header("Vary", "Accept")
+	ctx.Data(problem.Status, problemMediaType, data)
+}
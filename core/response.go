@@ -1,9 +1,10 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"reflect"
 	"strings"
 	"template/i18n"
 	"template/internal/builtin"
@@ -13,13 +14,15 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-var (
-	successResponse = gin.H{"code": 0, "message": i18n.I18N.T("Hints.Success")}
-	validatorInstance = validator.New()
-)
+var validatorInstance = validator.New()
+
+// successMessage 按本次请求的语言翻译成功信息
+func successMessage(ctx *gin.Context) string {
+	return i18n.T(i18n.LocaleFromContext(ctx.Request.Context()), "Hints.Success")
+}
 
 func Response(ctx *gin.Context, message string, data any, status int, code int) {
-	ctx.JSON(status, gin.H{
+	render(ctx, status, gin.H{
 		"code":    code,
 		"message": message,
 		"data":    data,
@@ -27,8 +30,17 @@ func Response(ctx *gin.Context, message string, data any, status int, code int)
 }
 
 func ResponseError(ctx *gin.Context, err error, format ...map[string]any) {
-	if customErr, ok := err.(*builtinerrors.Exception); ok {
-		customErr.Format(format...)
+	var customErr *builtinerrors.Exception
+	if errors.As(err, &customErr) {
+		// 模板在请求间共享, 只在副本上填充参数和语言
+		customErr = customErr.WithArgs(format...)
+		if customErr.Locale() == "" {
+			customErr = customErr.WithLocale(i18n.LocaleFromContext(ctx.Request.Context()))
+		}
+		if wantsProblem(ctx) {
+			renderProblem(ctx, customErr)
+			return
+		}
 		result := gin.H{
 			"code":    customErr.Code(),
 			"message": customErr.Error(),
@@ -38,12 +50,16 @@ func ResponseError(ctx *gin.Context, err error, format ...map[string]any) {
 			result["errors"] = errMsg
 		}
 
-		ctx.JSON(customErr.Status(), result)
+		render(ctx, customErr.Status(), result)
 		return
 	}
 
-	defaultErr := builtin.ErrInternalServer
-	ctx.JSON(defaultErr.Status(), gin.H{
+	defaultErr := builtin.ErrInternalServer.WithLocale(i18n.LocaleFromContext(ctx.Request.Context()))
+	if wantsProblem(ctx) {
+		renderProblem(ctx, defaultErr)
+		return
+	}
+	render(ctx, defaultErr.Status(), gin.H{
 		"code":    defaultErr.Code(),
 		"message": defaultErr.Error(),
 	})
@@ -57,105 +73,37 @@ func SetHeaders(ctx *gin.Context, headers map[string]string) {
 
 func ResponseData(ctx *gin.Context, data any) {
 	if data == nil {
-		ctx.JSON(http.StatusOK, successResponse)
+		render(ctx, http.StatusOK, gin.H{"code": 0, "message": successMessage(ctx)})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
+	render(ctx, http.StatusOK, gin.H{
 		"code":    0,
-		"message": successResponse["message"],
+		"message": successMessage(ctx),
 		"data":    data,
 	})
 }
 
-func ValidateParams[T any](bind T) error {
+// ValidateParams 校验参数, 字段错误信息使用 ctx 中的语言
+func ValidateParams[T any](ctx context.Context, bind T) error {
+	if !validatorInUse.Load() {
+		validatorInUse.Store(true)
+	}
 	err := validatorInstance.Struct(bind)
 	if err != nil {
-		parmeterErr := builtinerrors.New("Errors.ParameterIsInvalid", http.StatusBadRequest, builtin.ParameterIsInvalidCode)
+		locale := i18n.LocaleFromContext(ctx)
+		fields := make(map[string]string)
 		if validErr, ok := err.(validator.ValidationErrors); ok {
 			for _, val := range validErr {
 				trans := make(map[string]any)
-				path := ""
 				trans["field"] = strings.ToLower(val.Field())
 				trans["val"] = val.Param()
 				trans["tag"] = val.Tag()
-				switch val.Tag() {
-				case "lte":
-					path = "Validate.LessThanOrEqual"
-				case "gte":
-					path = "Validate.GreatThanOrEqual"
-				case "lt":
-					path = "Validate.LessThan"
-				case "required":
-					path = "Validate.Required"
-				case "min":
-					path = "Validate.Min"
-				case "max":
-					path = "Validate.Max"
-				case "oneof":
-					path = "Validate.Enum"
-				case "email":
-					path = "Validate.IsEmail"
-				case "isbn":
-					path = "Validate.ISBN"
-				case "html":
-					path = "Validate.HTML"
-				case "uuid":
-					path = "Validate.UUID"
-				case "md4":
-					path = "Validate.MD4"
-				case "md5":
-					path = "Validate.MD5"
-				case "cve":
-					path = "Validate.CVE"
-				case "country_code":
-					path = "Validate.Contry"
-				case "boolean":
-					path = "Validate.Boolean"
-				case "number", "numeric", "float":
-					path = "Validate.Numberic"
-				case "alpha", "alphaunicode":
-					path = "Validate.Alphabet"
-				case "btc_addr":
-					path = "Validate.BTC"
-				case "eth_addr":
-					path = "Validate.ETH"
-				case "hexadecimal":
-					path = "Validate.HEX"
-				case "semver":
-					path = "Validate.Semver"
-				case "credit_card":
-					path = "Validate.Credit"
-				case "upper_required":
-					path = "Validate.UpperCaseRequired"
-				case "lower_required":
-					path = "Validate.LowerCaseRequired"
-				case "numberic_required":
-					path = "Validate.NumberRequired"
-				case "special_char":
-					path = "Validate.SpecialChar"
-				case "common_password":
-					path = "Validate.CommonPassword"
-				case "datetime":
-					path = "Validate.Datetime"
-				default:
-					path = "Validate.Default"
-				}
-				if (val.Tag() == "min" || val.Tag() == "max") && (val.Kind() == reflect.Array || val.Kind() == reflect.String) {
-					switch val.Tag() {
-					case "min":
-						path = "Validate.MinLength"
-					case "max":
-						path = "Validate.MaxLength"
-					}
-				}
-				parmeterErr.SetErrorMessage(
-					strings.ToLower(val.Field()),
-					i18n.I18N.T(path, trans),
-				)
+				path := validateMessagePath(val.Tag(), val.Kind())
+				fields[strings.ToLower(val.Field())] = i18n.T(locale, path, trans)
 			}
 		}
-		return parmeterErr
+		return builtin.ErrInvalidParams.WithLocale(locale).WithFieldErrors(fields)
 	}
 	return nil
 }
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"template/i18n"
+	"template/internal/builtin"
+	builtinerrors "template/internal/builtinErrors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := i18n.Load("../locale", ""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+var testLocales = []string{i18n.LanguageEN, i18n.LanguageZH, "de", "fr", "ja", "ru"}
+
+type errorBody struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Errors  map[string]string `json:"errors"`
+}
+
+// respond 使用 locale 调用 handler, 返回解析后的响应
+func respond(t *testing.T, locale string, handler func(ctx *gin.Context)) (int, errorBody) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx.Request = request.WithContext(i18n.WithLocale(request.Context(), locale))
+	handler(ctx)
+
+	var body errorBody
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Errorf("%s: decode response: %v", locale, err)
+	}
+	return recorder.Code, body
+}
+
+type loginParams struct {
+	Username string `validate:"required"`
+	Password string `validate:"required,min=8"`
+}
+
+func TestResponseErrorConcurrentLocales(t *testing.T) {
+	want := make(map[string]string, len(testLocales))
+	for _, locale := range testLocales {
+		want[locale] = i18n.T(locale, builtin.ErrTokenInvalid.Path())
+	}
+
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locale := testLocales[i%len(testLocales)]
+			field := fmt.Sprintf("field%d", i)
+			status, body := respond(t, locale, func(ctx *gin.Context) {
+				ResponseError(ctx, builtin.ErrTokenInvalid.WithFieldError(field, locale))
+			})
+			if status != builtin.ErrTokenInvalid.Status() || body.Code != builtin.ErrTokenInvalid.Code() {
+				t.Errorf("%s: status=%d code=%d", locale, status, body.Code)
+			}
+			if body.Message != want[locale] {
+				t.Errorf("%s: message = %q, want %q", locale, body.Message, want[locale])
+			}
+			if len(body.Errors) != 1 || body.Errors[field] != locale {
+				t.Errorf("%s: errors = %v, want only %s", locale, body.Errors, field)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(builtin.ErrTokenInvalid.ErrorMessage()) != 0 || builtin.ErrTokenInvalid.Locale() != "" {
+		t.Fatal("shared error template was modified")
+	}
+}
+
+func TestValidateParamsConcurrentLocales(t *testing.T) {
+	want := make(map[string]string, len(testLocales))
+	for _, locale := range testLocales {
+		want[locale] = i18n.T(locale, "Validate.Required", map[string]any{"field": "username", "val": "", "tag": "required"})
+	}
+
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locale := testLocales[i%len(testLocales)]
+			err := ValidateParams(i18n.WithLocale(context.Background(), locale), loginParams{Password: "short"})
+			if !errors.Is(err, builtin.ErrInvalidParams) {
+				t.Errorf("%s: err = %v, want %v", locale, err, builtin.ErrInvalidParams)
+				return
+			}
+			var exception *builtinerrors.Exception
+			errors.As(err, &exception)
+			if exception.Locale() != locale {
+				t.Errorf("locale = %q, want %q", exception.Locale(), locale)
+			}
+			fields := exception.ErrorMessage()
+			if fields["username"] != want[locale] {
+				t.Errorf("%s: username = %q, want %q", locale, fields["username"], want[locale])
+			}
+			if fields["password"] == "" {
+				t.Errorf("%s: missing password error in %v", locale, fields)
+			}
+
+			_, body := respond(t, locale, func(ctx *gin.Context) { ResponseError(ctx, err) })
+			if body.Errors["username"] != want[locale] {
+				t.Errorf("%s: response errors = %v", locale, body.Errors)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestValidateParamsValid(t *testing.T) {
+	if err := ValidateParams(context.Background(), loginParams{Username: "alice", Password: "password"}); err != nil {
+		t.Fatalf("ValidateParams: %v", err)
+	}
+}
+
+func TestRegisterValidationAfterValidate(t *testing.T) {
+	if err := ValidateParams(context.Background(), loginParams{Username: "alice", Password: "password"}); err != nil {
+		t.Fatalf("ValidateParams: %v", err)
+	}
+	err := RegisterValidation("late", containsRune(isSpecialChar), "Validate.Late")
+	if !errors.Is(err, ErrValidatorInUse) {
+		t.Fatalf("RegisterValidation = %v, want %v", err, ErrValidatorInUse)
+	}
+}
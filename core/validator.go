@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+//go:embed data/common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords 常见弱密码, 比较时忽略大小写
+var commonPasswords = loadCommonPasswords(commonPasswordList)
+
+// ErrValidatorInUse 已经开始校验参数后再注册校验tag
+var ErrValidatorInUse = errors.New("core: register validation after validating has started")
+
+// validatorInUse 第一次调用 ValidateParams 后为 true, 之后不能再注册校验tag
+var validatorInUse atomic.Bool
+
+var (
+	validateMessagesMu sync.RWMutex
+	// validateMessages 校验tag对应的错误信息i18n路径
+	validateMessages = map[string]string{
+		"lte":          "Validate.LessThanOrEqual",
+		"gte":          "Validate.GreatThanOrEqual",
+		"lt":           "Validate.LessThan",
+		"required":     "Validate.Required",
+		"min":          "Validate.Min",
+		"max":          "Validate.Max",
+		"oneof":        "Validate.Enum",
+		"email":        "Validate.IsEmail",
+		"isbn":         "Validate.ISBN",
+		"html":         "Validate.HTML",
+		"uuid":         "Validate.UUID",
+		"md4":          "Validate.MD4",
+		"md5":          "Validate.MD5",
+		"cve":          "Validate.CVE",
+		"country_code": "Validate.Contry",
+		"boolean":      "Validate.Boolean",
+		"number":       "Validate.Numberic",
+		"numeric":      "Validate.Numberic",
+		"float":        "Validate.Numberic",
+		"alpha":        "Validate.Alphabet",
+		"alphaunicode": "Validate.Alphabet",
+		"btc_addr":     "Validate.BTC",
+		"eth_addr":     "Validate.ETH",
+		"hexadecimal":  "Validate.HEX",
+		"semver":       "Validate.Semver",
+		"credit_card":  "Validate.Credit",
+		"datetime":     "Validate.Datetime",
+	}
+	// lengthMessages 作用于字符串和数组时, 使用长度相关的错误信息
+	lengthMessages = map[string]string{
+		"min": "Validate.MinLength",
+		"max": "Validate.MaxLength",
+	}
+)
+
+func init() {
+	MustRegisterValidation("upper_required", containsRune(unicode.IsUpper), "Validate.UpperCaseRequired")
+	MustRegisterValidation("lower_required", containsRune(unicode.IsLower), "Validate.LowerCaseRequired")
+	MustRegisterValidation("numberic_required", containsRune(unicode.IsDigit), "Validate.NumberRequired")
+	MustRegisterValidation("special_char", containsRune(isSpecialChar), "Validate.SpecialChar")
+	MustRegisterValidation("common_password", notCommonPassword, "Validate.CommonPassword")
+}
+
+// RegisterValidation 注册自定义校验tag, 并指定校验失败时错误信息的i18n路径
+//
+// validator 的 RegisterValidation 与校验并发执行时不安全, 只能在 init 或启动时、处理请求之前调用,
+// 第一次调用 ValidateParams 之后再注册返回 ErrValidatorInUse
+//
+//	core.RegisterValidation("phone", isPhone, "Validate.Phone")
+func RegisterValidation(tag string, fn validator.Func, path string, callValidationEvenIfNull ...bool) error {
+	if validatorInUse.Load() {
+		return ErrValidatorInUse
+	}
+	if err := validatorInstance.RegisterValidation(tag, fn, callValidationEvenIfNull...); err != nil {
+		return err
+	}
+	if path != "" {
+		RegisterValidateMessage(tag, path)
+	}
+	return nil
+}
+
+// MustRegisterValidation 同 RegisterValidation, 注册失败时 panic
+func MustRegisterValidation(tag string, fn validator.Func, path string, callValidationEvenIfNull ...bool) {
+	if err := RegisterValidation(tag, fn, path, callValidationEvenIfNull...); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterValidateMessage 为已有的校验tag(包括validator内置tag)指定错误信息的i18n路径
+func RegisterValidateMessage(tag string, path string) {
+	validateMessagesMu.Lock()
+	defer validateMessagesMu.Unlock()
+	validateMessages[tag] = path
+}
+
+// validateMessagePath 查找校验tag对应的i18n路径
+func validateMessagePath(tag string, kind reflect.Kind) string {
+	validateMessagesMu.RLock()
+	defer validateMessagesMu.RUnlock()
+	if kind == reflect.Array || kind == reflect.Slice || kind == reflect.String {
+		if path, ok := lengthMessages[tag]; ok {
+			return path
+		}
+	}
+	if path, ok := validateMessages[tag]; ok {
+		return path
+	}
+	return "Validate.Default"
+}
+
+// containsRune 字符串中至少包含一个满足条件的字符
+func containsRune(match func(rune) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return strings.IndexFunc(fl.Field().String(), match) >= 0
+	}
+}
+
+func isSpecialChar(ch rune) bool {
+	return unicode.IsPunct(ch) || unicode.IsSymbol(ch)
+}
+
+// notCommonPassword 密码不在常见弱密码列表中
+func notCommonPassword(fl validator.FieldLevel) bool {
+	return !commonPasswords[strings.ToLower(fl.Field().String())]
+}
+
+func loadCommonPasswords(list string) map[string]bool {
+	result := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result[strings.ToLower(line)] = true
+	}
+	return result
+}
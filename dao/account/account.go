@@ -1,11 +1,13 @@
 package account
 
 import (
-	"template/global"
+	"context"
+	"template/common/query"
+	"template/dao/repository"
 	"template/model"
-)
 
-var db = global.DB
+	"gorm.io/gorm"
+)
 
 type IAccount interface {
     Create(account *model.AccountModel) error
@@ -15,39 +17,31 @@ type IAccount interface {
     List(page, size int) ([]*model.AccountModel, error)
 }
 
-type AccountDao struct {}
+var _ IAccount = (*AccountDao)(nil)
 
-func (d *AccountDao) Create(account *model.AccountModel) error {
-    return db.Create(account).Error
+// AccountDao 账号数据访问, 通用方法由 repository.Repository 提供
+type AccountDao struct {
+	repository.Repository[model.AccountModel]
 }
 
-func (d *AccountDao) Delete(gid string) error {
-    return db.Where("gid = ?", gid).Delete(&model.AccountModel{}).Error
+// New 使用 db 的 AccountDao, cursors 为游标分页的签名
+func New(db *gorm.DB, cursors *query.CursorCodec) *AccountDao {
+	return &AccountDao{Repository: *repository.New[model.AccountModel](db, cursors)}
 }
 
+// WithContext 返回绑定 ctx 中事务的 AccountDao, ctx 不在事务中时使用当前连接
+func (d *AccountDao) WithContext(ctx context.Context) *AccountDao {
+	return &AccountDao{Repository: *d.Repository.WithContext(ctx)}
+}
+
+// Search 按条件查找一个账号, 没有匹配的账号时返回 gorm.ErrRecordNotFound
 func (d *AccountDao) Search(values map[string]any) (*model.AccountModel, error) {
 	var account model.AccountModel
-	err := db.Where(values).Find(&account).Error
+	err := d.DB().Where(values).First(&account).Error
 	if err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-func (d *AccountDao) Update(account *model.AccountModel) error {
-    return db.Where("gid = ?", account.GID).Updates(account).Error
-}
-
-func (d *AccountDao) FindByGID(gid string) (*model.AccountModel, error) {
-    var account model.AccountModel
-    err := db.Where("gid = ?", gid).First(&account).Error
-    return &account, err
-}
-
-func (d *AccountDao) List(page, size int) ([]*model.AccountModel, error) {
-    var accounts []*model.AccountModel
-    err := db.Offset((page - 1) * size).Limit(size).Find(&accounts).Error
-    return accounts, err
-}
-
 
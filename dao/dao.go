@@ -2,13 +2,40 @@ package dao
 
 import (
 	"template/dao/account"
+	"template/dao/repository"
+	"template/dao/oauth"
+	"template/dao/role"
+	"template/dao/token"
 	"template/dao/user"
+
+	"gorm.io/gorm"
 )
 
 
 type Dao struct {
 	User user.UserDao
 	Account account.AccountDao
+	Role role.RoleDao
+	Token token.TokenDao
+	Oauth oauth.OauthDao
+
+	db *gorm.DB
 }
 
-var APIDao = new(Dao)
\ No newline at end of file
+// New 所有 DAO 使用同一个 db
+//
+// cursorSecret 用于签名游标分页的游标, 为空时返回 repository.ErrCursorKey
+func New(db *gorm.DB, cursorSecret string) (*Dao, error) {
+	cursors, err := repository.NewCursorCodec(cursorSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &Dao{
+		User:    *user.New(db, cursors),
+		Account: *account.New(db, cursors),
+		Role:    *role.New(db),
+		Token:   *token.New(db),
+		Oauth:   *oauth.New(db),
+		db:      db,
+	}, nil
+}
\ No newline at end of file
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"context"
+	"template/dao/uow"
+	"template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IOauth interface {
+	CreateClient(client *model.OauthClientModel) error
+	FindClient(clientID string) (*model.OauthClientModel, error)
+	ListClients(ownerGID string) ([]*model.OauthClientModel, error)
+	DeleteClient(clientID string) error
+	SaveCode(code *model.OauthCodeModel) error
+	ConsumeCode(codeHash string, at time.Time) (*model.OauthCodeModel, bool, error)
+	FindIdentity(provider, subject string) (*model.AccountIdentityModel, error)
+	SaveIdentity(identity *model.AccountIdentityModel) error
+}
+
+type OauthDao struct {
+	db *gorm.DB
+}
+
+// New 使用 db 的 OauthDao
+func New(db *gorm.DB) *OauthDao {
+	return &OauthDao{db: db}
+}
+
+// WithContext 返回绑定 ctx 中事务的 OauthDao, ctx 不在事务中时使用当前连接
+func (d *OauthDao) WithContext(ctx context.Context) *OauthDao {
+	return &OauthDao{db: uow.FromDB(ctx, d.db)}
+}
+
+// conn 创建时的连接或 WithContext 绑定的事务
+func (d *OauthDao) conn() *gorm.DB {
+	return d.db
+}
+
+func (d *OauthDao) CreateClient(client *model.OauthClientModel) error {
+	return d.conn().Create(client).Error
+}
+
+func (d *OauthDao) FindClient(clientID string) (*model.OauthClientModel, error) {
+	var client model.OauthClientModel
+	err := d.conn().Where("client_id = ?", clientID).First(&client).Error
+	return &client, err
+}
+
+// ListClients ownerGID 为空时返回全部客户端
+func (d *OauthDao) ListClients(ownerGID string) ([]*model.OauthClientModel, error) {
+	var clients []*model.OauthClientModel
+	query := d.conn().Order("id")
+	if ownerGID != "" {
+		query = query.Where("owner_gid = ?", ownerGID)
+	}
+	err := query.Find(&clients).Error
+	return clients, err
+}
+
+func (d *OauthDao) DeleteClient(clientID string) error {
+	return d.conn().Where("client_id = ?", clientID).Delete(&model.OauthClientModel{}).Error
+}
+
+func (d *OauthDao) SaveCode(code *model.OauthCodeModel) error {
+	return d.conn().Create(code).Error
+}
+
+// ConsumeCode 将授权码标记为已使用, 授权码已被使用过时第二个返回值为 false
+func (d *OauthDao) ConsumeCode(codeHash string, at time.Time) (*model.OauthCodeModel, bool, error) {
+	var code model.OauthCodeModel
+	if err := d.conn().Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+		return nil, false, err
+	}
+	result := d.conn().Model(&model.OauthCodeModel{}).
+		Where("id = ? AND used_at IS NULL", code.ID).
+		Update("used_at", at)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return &code, result.RowsAffected == 1, nil
+}
+
+// FindIdentity 按提供方和提供方用户ID查找关联的账号
+func (d *OauthDao) FindIdentity(provider, subject string) (*model.AccountIdentityModel, error) {
+	var identity model.AccountIdentityModel
+	err := d.conn().Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+func (d *OauthDao) SaveIdentity(identity *model.AccountIdentityModel) error {
+	return d.conn().Create(identity).Error
+}
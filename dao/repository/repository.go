@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"reflect"
+	"template/common/query"
+	"template/dao/uow"
+
+	"golang.org/x/crypto/hkdf"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// GIDColumn 全局唯一ID列
+	GIDColumn = "gid"
+	// VersionColumn 乐观锁版本号列
+	VersionColumn = "version"
+
+	defaultPageSize = 10
+)
+
+// ErrVersionConflict 记录已被其他请求修改, 或记录不存在
+var ErrVersionConflict = errors.New("repository: version conflict")
+
+// ErrCursorKey 没有配置游标签名密钥
+var ErrCursorKey = errors.New("repository: cursor signing key is not set")
+
+// cursorKeyInfo 派生游标签名密钥的 HKDF info, 同一个 secret 用于其他用途时派生出的密钥互不相同
+const cursorKeyInfo = "template/cursor"
+
+// NewCursorCodec 使用 HKDF-SHA256 由 secret 派生游标签名密钥, 所有实例使用相同的 secret 时游标可以互相使用
+func NewCursorCodec(secret string) (*query.CursorCodec, error) {
+	if secret == "" {
+		return nil, ErrCursorKey
+	}
+	key := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(cursorKeyInfo)), key); err != nil {
+		return nil, err
+	}
+	return query.NewCursorCodec(key), nil
+}
+
+// Repository 模型 T 的通用数据访问, T 需要有 gid 列, 嵌入 gorm.Model 时自动支持软删除
+type Repository[T any] struct {
+	db      *gorm.DB
+	cursors *query.CursorCodec
+}
+
+// New 使用 db 的 Repository, cursors 为游标分页的签名, 为空时 Seek 返回 ErrCursorKey
+func New[T any](db *gorm.DB, cursors *query.CursorCodec) *Repository[T] {
+	return &Repository[T]{db: db, cursors: cursors}
+}
+
+// WithContext 返回绑定 ctx 中事务的 Repository, ctx 不在事务中时使用当前连接
+func (r *Repository[T]) WithContext(ctx context.Context) *Repository[T] {
+	return &Repository[T]{db: uow.FromDB(ctx, r.db), cursors: r.cursors}
+}
+
+// DB 创建时的连接或 WithContext 绑定的事务, 用于编写 Repository 之外的查询
+func (r *Repository[T]) DB() *gorm.DB {
+	return r.db
+}
+
+// Unscoped 返回包含已软删除记录的 Repository, 此时 Delete 会物理删除
+func (r *Repository[T]) Unscoped() *Repository[T] {
+	return &Repository[T]{db: r.DB().Unscoped(), cursors: r.cursors}
+}
+
+func (r *Repository[T]) model() *gorm.DB {
+	return r.DB().Model(new(T))
+}
+
+func (r *Repository[T]) Create(entity *T) error {
+	return r.DB().Create(entity).Error
+}
+
+// CreateBatch 分批插入, batchSize 不大于0时一次插入
+func (r *Repository[T]) CreateBatch(entities []*T, batchSize int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = len(entities)
+	}
+	return r.DB().CreateInBatches(entities, batchSize).Error
+}
+
+// Upsert 插入记录, conflict 列冲突时更新 columns 中的列, columns 为空时更新全部列
+func (r *Repository[T]) Upsert(entities []*T, conflict []string, columns ...string) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	onConflict := clause.OnConflict{UpdateAll: len(columns) == 0}
+	for _, name := range conflict {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	if len(columns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(columns)
+	}
+	return r.DB().Clauses(onConflict).Create(&entities).Error
+}
+
+// Delete 按 gid 删除, 模型支持软删除时为软删除
+func (r *Repository[T]) Delete(gid string) error {
+	return r.DB().Where(GIDColumn+" = ?", gid).Delete(new(T)).Error
+}
+
+// Restore 恢复已软删除的记录
+func (r *Repository[T]) Restore(gid string) error {
+	return r.DB().Unscoped().Model(new(T)).Where(GIDColumn+" = ?", gid).Update("deleted_at", nil).Error
+}
+
+// Update 按 entity 的 gid 更新非零值字段
+func (r *Repository[T]) Update(entity *T) error {
+	gid, err := r.value(entity, GIDColumn)
+	if err != nil {
+		return err
+	}
+	return r.model().Where(GIDColumn+" = ?", gid).Updates(entity).Error
+}
+
+// UpdateFields 只更新 fields 中的字段, 允许将字段更新为零值
+func (r *Repository[T]) UpdateFields(gid string, fields map[string]any) error {
+	return r.model().Where(GIDColumn+" = ?", gid).Updates(fields).Error
+}
+
+// UpdateVersioned 按 entity 的 gid 和版本号更新非零值字段, 成功后 entity 的版本号加一
+//
+// 版本号不一致时返回 ErrVersionConflict
+func (r *Repository[T]) UpdateVersioned(entity *T) error {
+	parsed, err := r.parse(entity)
+	if err != nil {
+		return err
+	}
+	field := parsed.LookUpField(VersionColumn)
+	if field == nil {
+		return gorm.ErrInvalidField
+	}
+	gid, err := r.value(entity, GIDColumn)
+	if err != nil {
+		return err
+	}
+	ctx, target := context.Background(), reflect.ValueOf(entity)
+	version, _ := field.ValueOf(ctx, target)
+	value := reflect.ValueOf(version)
+	if !value.CanInt() {
+		return gorm.ErrInvalidField
+	}
+	current := value.Int()
+	if err := field.Set(ctx, target, current+1); err != nil {
+		return err
+	}
+	result := r.model().Where(GIDColumn+" = ? AND "+VersionColumn+" = ?", gid, current).Updates(entity)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		_ = field.Set(ctx, target, current)
+	}
+	return result.Error
+}
+
+// UpdateFieldsVersioned 版本号为 version 时更新 fields 中的字段并将版本号加一
+//
+// 版本号不一致时返回 ErrVersionConflict
+func (r *Repository[T]) UpdateFieldsVersioned(gid string, version int64, fields map[string]any) error {
+	updates := make(map[string]any, len(fields)+1)
+	for column, value := range fields {
+		updates[column] = value
+	}
+	updates[VersionColumn] = gorm.Expr(VersionColumn + " + 1")
+	result := r.model().Where(GIDColumn+" = ? AND "+VersionColumn+" = ?", gid, version).Updates(updates)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return result.Error
+}
+
+func (r *Repository[T]) FindByGID(gid string) (*T, error) {
+	entity := new(T)
+	err := r.DB().Where(GIDColumn+" = ?", gid).First(entity).Error
+	return entity, err
+}
+
+// Find 按条件查询, conds 与 gorm 的 Find 相同
+func (r *Repository[T]) Find(conds ...any) ([]*T, error) {
+	var entities []*T
+	err := r.DB().Find(&entities, conds...).Error
+	return entities, err
+}
+
+func (r *Repository[T]) List(page, size int) ([]*T, error) {
+	page, size = normalize(page, size)
+	var entities []*T
+	err := r.DB().Offset((page - 1) * size).Limit(size).Find(&entities).Error
+	return entities, err
+}
+
+// Count 记录总数, 不包含已软删除的记录
+func (r *Repository[T]) Count() (int64, error) {
+	var total int64
+	err := r.model().Count(&total).Error
+	return total, err
+}
+
+// Page 分页查询, 同时返回记录总数
+func (r *Repository[T]) Page(page, size int) ([]*T, int64, error) {
+	total, err := r.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	entities, err := r.List(page, size)
+	return entities, total, err
+}
+
+// Query 按 q 过滤、排序和分页, 同时返回符合条件的记录总数
+func (r *Repository[T]) Query(q *query.Query) ([]*T, int64, error) {
+	var total int64
+	if err := r.model().Scopes(q.Scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entities []*T
+	err := r.DB().Scopes(q.Scope, q.Paginate).Find(&entities).Error
+	return entities, total, err
+}
+
+// Seek 按 q 游标分页查询, 不返回记录总数
+func (r *Repository[T]) Seek(q *query.Query) ([]*T, query.Cursors, error) {
+	if r.cursors == nil {
+		return nil, query.Cursors{}, ErrCursorKey
+	}
+	return query.Seek[T](r.DB(), q, r.cursors)
+}
+
+func (r *Repository[T]) parse(entity *T) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.DB()}
+	if err := stmt.Parse(entity); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// value 读取 entity 中 column 列的值
+func (r *Repository[T]) value(entity *T, column string) (any, error) {
+	parsed, err := r.parse(entity)
+	if err != nil {
+		return nil, err
+	}
+	field := parsed.LookUpField(column)
+	if field == nil {
+		return nil, gorm.ErrInvalidField
+	}
+	value, _ := field.ValueOf(context.Background(), reflect.ValueOf(entity))
+	return value, nil
+}
+
+func normalize(page, size int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	return page, size
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"errors"
+	"template/common/query"
+	"testing"
+)
+
+func TestNewCursorCodec(t *testing.T) {
+	if _, err := NewCursorCodec(""); !errors.Is(err, ErrCursorKey) {
+		t.Fatalf("empty secret: err = %v, want %v", err, ErrCursorKey)
+	}
+
+	codec, err := NewCursorCodec("secret")
+	if err != nil {
+		t.Fatalf("NewCursorCodec: %v", err)
+	}
+	cursor := query.Cursor{Sort: "id", Values: []string{"1"}}
+	token, err := codec.Encode(cursor)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// 相同的 secret 派生相同的密钥, 多个实例之间游标通用
+	other, _ := NewCursorCodec("secret")
+	if _, err := other.Decode(token); err != nil {
+		t.Fatalf("Decode with the same secret: %v", err)
+	}
+
+	// 直接使用 secret 签名的游标(如令牌签名密钥)不能通过校验
+	raw, _ := query.NewCursorCodec([]byte("secret")).Encode(cursor)
+	if _, err := codec.Decode(raw); !errors.Is(err, query.ErrInvalidQuery) {
+		t.Fatalf("cursor signed with the raw secret: err = %v, want %v", err, query.ErrInvalidQuery)
+	}
+	changed, _ := NewCursorCodec("other")
+	if _, err := changed.Decode(token); !errors.Is(err, query.ErrInvalidQuery) {
+		t.Fatalf("cursor from another secret: err = %v, want %v", err, query.ErrInvalidQuery)
+	}
+}
@@ -0,0 +1,121 @@
+package role
+
+import (
+	"context"
+	"template/dao/uow"
+	"template/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IRole interface {
+	Create(role *model.RoleModel) error
+	Delete(name string) error
+	Update(role *model.RoleModel) error
+	FindByName(name string) (*model.RoleModel, error)
+	FindByNames(names []string) ([]*model.RoleModel, error)
+	List() ([]*model.RoleModel, error)
+	Grant(accountGID, roleName string) error
+	Revoke(accountGID, roleName string) error
+	RolesOf(accountGID string) ([]string, error)
+	SavePermissions(permissions []*model.PermissionModel) error
+}
+
+type RoleDao struct {
+	db *gorm.DB
+}
+
+// New 使用 db 的 RoleDao
+func New(db *gorm.DB) *RoleDao {
+	return &RoleDao{db: db}
+}
+
+// WithContext 返回绑定 ctx 中事务的 RoleDao, ctx 不在事务中时使用当前连接
+func (d *RoleDao) WithContext(ctx context.Context) *RoleDao {
+	return &RoleDao{db: uow.FromDB(ctx, d.db)}
+}
+
+// conn 创建时的连接或 WithContext 绑定的事务
+func (d *RoleDao) conn() *gorm.DB {
+	return d.db
+}
+
+func (d *RoleDao) Create(role *model.RoleModel) error {
+	return d.conn().Create(role).Error
+}
+
+// Delete 在同一个事务中删除角色及其授予记录
+//
+// name 上有唯一索引, 软删除会使同名角色无法再次创建, 因此直接删除记录
+func (d *RoleDao) Delete(name string) error {
+	return d.conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("role_name = ?", name).Delete(&model.AccountRoleModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("name = ?", name).Delete(&model.RoleModel{}).Error
+	})
+}
+
+func (d *RoleDao) Update(role *model.RoleModel) error {
+	return d.conn().Model(&model.RoleModel{}).Where("name = ?", role.Name).Updates(map[string]any{
+		"description": role.Description,
+		"permission":  role.Permission,
+	}).Error
+}
+
+func (d *RoleDao) FindByName(name string) (*model.RoleModel, error) {
+	var role model.RoleModel
+	err := d.conn().Where("name = ?", name).First(&role).Error
+	return &role, err
+}
+
+func (d *RoleDao) FindByNames(names []string) ([]*model.RoleModel, error) {
+	var roles []*model.RoleModel
+	if len(names) == 0 {
+		return roles, nil
+	}
+	err := d.conn().Where("name IN ?", names).Find(&roles).Error
+	return roles, err
+}
+
+func (d *RoleDao) List() ([]*model.RoleModel, error) {
+	var roles []*model.RoleModel
+	err := d.conn().Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (d *RoleDao) Grant(accountGID, roleName string) error {
+	var count int64
+	err := d.conn().Model(&model.AccountRoleModel{}).
+		Where("account_gid = ? AND role_name = ?", accountGID, roleName).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	return d.conn().Create(&model.AccountRoleModel{AccountGID: accountGID, RoleName: roleName}).Error
+}
+
+func (d *RoleDao) Revoke(accountGID, roleName string) error {
+	return d.conn().Unscoped().Where("account_gid = ? AND role_name = ?", accountGID, roleName).
+		Delete(&model.AccountRoleModel{}).Error
+}
+
+func (d *RoleDao) RolesOf(accountGID string) ([]string, error) {
+	var names []string
+	err := d.conn().Model(&model.AccountRoleModel{}).
+		Where("account_gid = ?", accountGID).
+		Pluck("role_name", &names).Error
+	return names, err
+}
+
+// SavePermissions 按 code 写入或更新权限定义
+func (d *RoleDao) SavePermissions(permissions []*model.PermissionModel) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	return d.conn().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"bit", "description", "updated_at"}),
+	}).Create(&permissions).Error
+}
@@ -0,0 +1,84 @@
+package token
+
+import (
+	"context"
+	"template/dao/uow"
+	"template/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IToken interface {
+	SaveRefresh(token *model.RefreshTokenModel) error
+	FindRefresh(jti string) (*model.RefreshTokenModel, error)
+	MarkUsed(jti, replacedBy string, at time.Time) (bool, error)
+	RevokeFamily(familyID string, until time.Time) error
+	Deny(id string, until time.Time) error
+	IsDenied(ids ...string) (bool, error)
+}
+
+// TokenDao 基于数据库的令牌存储
+type TokenDao struct {
+	db *gorm.DB
+}
+
+// New 使用 db 的 TokenDao
+func New(db *gorm.DB) *TokenDao {
+	return &TokenDao{db: db}
+}
+
+// WithContext 返回绑定 ctx 中事务的 TokenDao, ctx 不在事务中时使用当前连接
+func (d *TokenDao) WithContext(ctx context.Context) *TokenDao {
+	return &TokenDao{db: uow.FromDB(ctx, d.db)}
+}
+
+// conn 创建时的连接或 WithContext 绑定的事务
+func (d *TokenDao) conn() *gorm.DB {
+	return d.db
+}
+
+func (d *TokenDao) SaveRefresh(token *model.RefreshTokenModel) error {
+	return d.conn().Create(token).Error
+}
+
+func (d *TokenDao) FindRefresh(jti string) (*model.RefreshTokenModel, error) {
+	var token model.RefreshTokenModel
+	err := d.conn().Where("jti = ?", jti).First(&token).Error
+	return &token, err
+}
+
+// MarkUsed 将未使用的刷新令牌标记为已轮换, 令牌已被使用或吊销时返回 false
+func (d *TokenDao) MarkUsed(jti, replacedBy string, at time.Time) (bool, error) {
+	result := d.conn().Model(&model.RefreshTokenModel{}).
+		Where("jti = ? AND used_at IS NULL AND revoked_at IS NULL", jti).
+		Updates(map[string]any{"used_at": at, "replaced_by": replacedBy})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeFamily 吊销令牌族中的所有刷新令牌, 并将令牌族ID加入黑名单
+func (d *TokenDao) RevokeFamily(familyID string, until time.Time) error {
+	err := d.conn().Model(&model.RefreshTokenModel{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return d.Deny(familyID, until)
+}
+
+func (d *TokenDao) Deny(id string, until time.Time) error {
+	return d.conn().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at", "updated_at"}),
+	}).Create(&model.TokenDenylistModel{TokenID: id, ExpiresAt: until}).Error
+}
+
+func (d *TokenDao) IsDenied(ids ...string) (bool, error) {
+	var count int64
+	err := d.conn().Model(&model.TokenDenylistModel{}).
+		Where("token_id IN ? AND expires_at > ?", ids, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
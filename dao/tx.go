@@ -0,0 +1,28 @@
+package dao
+
+import (
+	"context"
+	"template/dao/uow"
+)
+
+// WithTx 在 Dao 的连接上开启事务执行 fn, 参见 uow.WithTxDB
+//
+//	err := d.WithTx(ctx, func(ctx context.Context) error {
+//		tx := d.WithContext(ctx)
+//		...
+//	})
+func (d *Dao) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return uow.WithTxDB(ctx, d.db, fn)
+}
+
+// WithContext 返回绑定 ctx 中事务的 Dao, ctx 不在事务中时使用当前连接
+func (d *Dao) WithContext(ctx context.Context) *Dao {
+	return &Dao{
+		db:      d.db,
+		User:    *d.User.WithContext(ctx),
+		Account: *d.Account.WithContext(ctx),
+		Role:    *d.Role.WithContext(ctx),
+		Token:   *d.Token.WithContext(ctx),
+		Oauth:   *d.Oauth.WithContext(ctx),
+	}
+}
@@ -0,0 +1,33 @@
+package uow
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// FromDB 返回 ctx 中的事务, ctx 不在事务中时返回绑定 ctx 的 db
+func FromDB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+// InTx ctx 是否处于 WithTx 开启的事务中
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return ok
+}
+
+// WithTxDB 在事务中执行 fn, ctx 不在事务中时在 db 上开启事务, fn 中的 DAO 需要通过参数 ctx 绑定事务
+//
+// fn 返回错误或 panic 时回滚, panic 会在回滚后继续抛出;
+// ctx 已处于事务中时使用保存点, 只回滚 fn 中的修改
+func WithTxDB(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	return FromDB(ctx, db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,211 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+// recorder 记录执行的语句, 代替数据库连接
+type recorder struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (r *recorder) record(statement string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, statement)
+}
+
+func (r *recorder) statements() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.log...)
+}
+
+func (r *recorder) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (r *recorder) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errors.New("query is not supported")
+}
+
+func (r *recorder) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return nil
+}
+
+func (r *recorder) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	r.record("BEGIN")
+	return &recorderTx{r}, nil
+}
+
+type recorderTx struct {
+	*recorder
+}
+
+func (tx *recorderTx) Commit() error {
+	tx.record("COMMIT")
+	return nil
+}
+
+func (tx *recorderTx) Rollback() error {
+	tx.record("ROLLBACK")
+	return nil
+}
+
+// savePointDialector 支持保存点的 DummyDialector
+type savePointDialector struct {
+	tests.DummyDialector
+}
+
+func (savePointDialector) SavePoint(tx *gorm.DB, name string) error {
+	return tx.Exec("SAVEPOINT sp").Error
+}
+
+func (savePointDialector) RollbackTo(tx *gorm.DB, name string) error {
+	return tx.Exec("ROLLBACK TO SAVEPOINT sp").Error
+}
+
+func newTestDB(t *testing.T) (*gorm.DB, *recorder) {
+	t.Helper()
+	conn := new(recorder)
+	db, err := gorm.Open(savePointDialector{}, &gorm.Config{ConnPool: conn, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return db, conn
+}
+
+// exec 使用 ctx 中的事务执行语句
+func exec(ctx context.Context, db *gorm.DB, statement string) error {
+	return FromDB(ctx, db).Exec(statement).Error
+}
+
+func TestWithTxDB(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := map[string]struct {
+		fn   func(ctx context.Context, db *gorm.DB) error
+		err  error
+		want []string
+	}{
+		"commit": {
+			func(ctx context.Context, db *gorm.DB) error {
+				return exec(ctx, db, "INSERT a")
+			},
+			nil,
+			[]string{"BEGIN", "INSERT a", "COMMIT"},
+		},
+		"rollback on error": {
+			func(ctx context.Context, db *gorm.DB) error {
+				exec(ctx, db, "INSERT a")
+				return errFailed
+			},
+			errFailed,
+			[]string{"BEGIN", "INSERT a", "ROLLBACK"},
+		},
+		"nested rollback to savepoint": {
+			func(ctx context.Context, db *gorm.DB) error {
+				exec(ctx, db, "INSERT a")
+				err := WithTxDB(ctx, db, func(ctx context.Context) error {
+					exec(ctx, db, "INSERT b")
+					return errFailed
+				})
+				if !errors.Is(err, errFailed) {
+					return errors.New("nested error was lost")
+				}
+				return exec(ctx, db, "INSERT c")
+			},
+			nil,
+			[]string{"BEGIN", "INSERT a", "SAVEPOINT sp", "INSERT b", "ROLLBACK TO SAVEPOINT sp", "INSERT c", "COMMIT"},
+		},
+		"nested commit": {
+			func(ctx context.Context, db *gorm.DB) error {
+				return WithTxDB(ctx, db, func(ctx context.Context) error {
+					return exec(ctx, db, "INSERT b")
+				})
+			},
+			nil,
+			[]string{"BEGIN", "SAVEPOINT sp", "INSERT b", "COMMIT"},
+		},
+		"nested error rolls back outer": {
+			func(ctx context.Context, db *gorm.DB) error {
+				return WithTxDB(ctx, db, func(ctx context.Context) error {
+					exec(ctx, db, "INSERT b")
+					return errFailed
+				})
+			},
+			errFailed,
+			[]string{"BEGIN", "SAVEPOINT sp", "INSERT b", "ROLLBACK TO SAVEPOINT sp", "ROLLBACK"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, conn := newTestDB(t)
+			err := WithTxDB(context.Background(), db, func(ctx context.Context) error {
+				return test.fn(ctx, db)
+			})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("WithTxDB err = %v, want %v", err, test.err)
+			}
+			if got := conn.statements(); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("statements = %s\nwant         %s", strings.Join(got, "; "), strings.Join(test.want, "; "))
+			}
+		})
+	}
+}
+
+func TestWithTxDBPanic(t *testing.T) {
+	db, conn := newTestDB(t)
+	defer func() {
+		if recovered := recover(); recovered != "boom" {
+			t.Fatalf("recovered %v, want the original panic", recovered)
+		}
+		want := []string{"BEGIN", "INSERT a", "ROLLBACK"}
+		if got := conn.statements(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("statements = %v, want %v", got, want)
+		}
+	}()
+	WithTxDB(context.Background(), db, func(ctx context.Context) error {
+		exec(ctx, db, "INSERT a")
+		panic("boom")
+	})
+}
+
+func TestFromDB(t *testing.T) {
+	db, conn := newTestDB(t)
+	ctx := context.Background()
+	if InTx(ctx) {
+		t.Fatal("InTx outside a transaction = true")
+	}
+	if err := exec(ctx, db, "INSERT outside"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	WithTxDB(ctx, db, func(txCtx context.Context) error {
+		if !InTx(txCtx) {
+			t.Error("InTx inside a transaction = false")
+		}
+		if FromDB(txCtx, db) == db {
+			t.Error("FromDB inside a transaction returned the connection")
+		}
+		return nil
+	})
+	want := []string{"INSERT outside", "BEGIN", "COMMIT"}
+	if got := conn.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %v, want %v", got, want)
+	}
+}
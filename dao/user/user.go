@@ -1,13 +1,13 @@
 package user
 
 import (
-	"fmt"
-	"strings"
-	"template/global"
+	"context"
+	"template/common/query"
+	"template/dao/repository"
 	"template/model"
-)
 
-var db = global.DB
+	"gorm.io/gorm"
+)
 
 type IUser interface {
     Create(user *model.UserModel) error
@@ -15,86 +15,27 @@ type IUser interface {
     Update(user *model.UserModel) error
     FindByGID(gid string) (*model.UserModel, error)
     List(page, size int) ([]*model.UserModel, error)
+    Count() (int64, error)
+    Page(page, size int) ([]*model.UserModel, int64, error)
+    Query(q *query.Query) ([]*model.UserModel, int64, error)
+    Seek(q *query.Query) ([]*model.UserModel, query.Cursors, error)
+    UpdateFields(gid string, fields map[string]any) error
+    UpdateFieldsVersioned(gid string, version int64, fields map[string]any) error
 }
 
-type UserDao struct {}
+var _ IUser = (*UserDao)(nil)
 
-func (d *UserDao) Create(user *model.UserModel) error {
-    return db.Create(user).Error
+// UserDao 用户数据访问, 通用方法由 repository.Repository 提供
+type UserDao struct {
+	repository.Repository[model.UserModel]
 }
 
-func (d *UserDao) Delete(gid string) error {
-    return db.Where("gid = ?", gid).Delete(&model.UserModel{}).Error
+// New 使用 db 的 UserDao, cursors 为游标分页的签名
+func New(db *gorm.DB, cursors *query.CursorCodec) *UserDao {
+	return &UserDao{Repository: *repository.New[model.UserModel](db, cursors)}
 }
 
-func (d *UserDao) Update(user *model.UserModel) error {
-    return db.Where("gid = ?", user.GID).Updates(user).Error
+// WithContext 返回绑定 ctx 中事务的 UserDao, ctx 不在事务中时使用当前连接
+func (d *UserDao) WithContext(ctx context.Context) *UserDao {
+	return &UserDao{Repository: *d.Repository.WithContext(ctx)}
 }
-
-func (d *UserDao) FindByGID(gid string) (*model.UserModel, error) {
-    var user model.UserModel
-    err := db.Where("gid = ?", gid).First(&user).Error
-    return &user, err
-}
-
-func (d *UserDao) List(page, size int) ([]*model.UserModel, error) {
-    var users []*model.UserModel
-    err := db.Offset((page - 1) * size).Limit(size).Find(&users).Error
-    return users, err
-}
-
-func (d *UserDao) Search(values map[string]string,page,size int) ([]*model.UserModel, error) {
-	result := []*model.UserModel{}
-	statement := db.Model(&model.UserModel{})
-	inited := false
-
-	// 默认分页参数
-	if page <= 0 {
-		page = 1
-	}
-	if size <= 0 {
-		size = 10 // 设置默认页大小为10
-	}
-
-	for key, value := range values {
-		// 排除分页相关的字段
-		if key == "page" || key == "size" {
-			continue
-		}
-
-		// 基本条件：查找匹配的字段
-		condition := key + " = ?"
-		// 处理 "!" 表示不等于
-		if strings.Contains(fmt.Sprintf("%v", value), "!") {
-			condition = key + " != ?"
-			value = strings.Replace(fmt.Sprintf("%v", value), "!", "", 1)
-		}
-
-		// 处理 LIKE 操作
-		if strings.Contains(fmt.Sprintf("%v", value), "%") {
-			// value like %"name"
-			value = strings.Replace(fmt.Sprintf("%v", value), "%", "", 1)
-			statement = statement.Where(key+" LIKE ?", "%"+value+"%")
-			continue
-		}
-
-		// 第一次初始化条件
-		if !inited {
-			statement = statement.Where(condition, value)
-			statement = statement.Offset((page - 1) * size).Limit(size)
-			inited = true
-			continue
-		}
-
-		// 处理 OR 条件
-		statement = statement.Or(condition, value)
-	}
-
-	err := statement.Find(&result).Error
-	if err != nil {
-		return nil, err
-	}
-
-	return result, nil
-}
-
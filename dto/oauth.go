@@ -0,0 +1,25 @@
+package dto
+
+type OauthClientCreateDTO struct {
+	Name         string   `json:"name" validate:"required,min=2,max=64"`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,url"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code client_credentials refresh_token"`
+	Scopes       []string `json:"scopes"`
+	Public       bool     `json:"public"`
+}
+
+type OauthClientDeleteDTO struct {
+	ClientID string `json:"client_id" validate:"required"`
+}
+
+// OauthConsentDTO 用户对授权请求的决定
+type OauthConsentDTO struct {
+	ResponseType        string `json:"response_type" validate:"required"`
+	ClientID            string `json:"client_id" validate:"required"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "template/common/query"
+
+// UserQuery 用户列表允许过滤和排序的字段
+var UserQuery = query.NewSchema(
+	query.WithField("id", query.Field{Column: "id", Kind: query.Int, Operators: []query.Operator{query.Eq, query.In, query.Lt, query.Gt}, Sortable: true}),
+	query.WithField("gid", query.Field{Column: "gid", Operators: []query.Operator{query.Eq, query.In}}),
+	query.WithField("name", query.Field{Column: "name", Sortable: true}),
+	query.WithField("email", query.Field{Column: "email", Sortable: true}),
+	query.WithField("country", query.Field{Column: "country_code", Operators: []query.Operator{query.Eq, query.Ne, query.In, query.IsNull}}),
+	query.WithField("gender", query.Field{Column: "gender", Operators: []query.Operator{query.Eq, query.Ne, query.In}}),
+	query.WithField("follows", query.Field{Column: "follows", Kind: query.Int, Sortable: true}),
+	query.WithField("following", query.Field{Column: "following", Kind: query.Int, Sortable: true}),
+	query.WithField("created_at", query.Field{Column: "created_at", Kind: query.Time, Sortable: true}),
+	query.WithField("updated_at", query.Field{Column: "updated_at", Kind: query.Time, Sortable: true}),
+	query.WithDefaultSort("id"),
+)
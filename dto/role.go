@@ -0,0 +1,18 @@
+package dto
+
+type RoleCreateDTO struct {
+	Name        string   `json:"name" validate:"required,min=2,max=64"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleUpdateDTO struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleGrantDTO struct {
+	GID  string `json:"gid" validate:"required"`
+	Role string `json:"role" validate:"required"`
+}
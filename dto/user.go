@@ -2,12 +2,30 @@ package dto
 
 type UserLoginDTO struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"min=12,max=30"`
+	Password string `json:"password" validate:"required,max=64"`
 }
 
 type UserCreateDTO struct {
 	Name     string `json:"name" validate:"required,min=4,max=20"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required,min=8,max=64,upper_required,numberic_required,special_char,common_password"`
 	Country  string `json:"country"`
+}
+
+// UserUpdateDTO 部分更新, 未传的字段保持不变
+type UserUpdateDTO struct {
+	GID         string  `json:"gid" validate:"required"`
+	Name        *string `json:"name" validate:"omitempty,min=4,max=20"`
+	Description *string `json:"description" validate:"omitempty,max=255"`
+	Phone       *string `json:"phone" validate:"omitempty,max=32"`
+	Country     *string `json:"country" validate:"omitempty,max=8"`
+	Gender      *string `json:"gender" validate:"omitempty,max=16"`
+	Avatar      *string `json:"avatar" validate:"omitempty,url"`
+	Language    *string `json:"language" validate:"omitempty,max=16"`
+	// Version 当前版本号, 提供时与数据库中的版本号不一致则拒绝修改
+	Version     *int64  `json:"version" validate:"omitempty,min=0"`
+}
+
+type UserDeleteDTO struct {
+	GID string `json:"gid" validate:"required"`
 }
\ No newline at end of file
@@ -1,46 +1,86 @@
 package config
 
-import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"template/common/dotenv"
-
-	"github.com/jingyuexing/go-utils"
-)
-
 type BaseEnv struct {
 	ConfigurePath  string `json:"config" env:"config"`
 	Mode           string `json:"mode" env:"mode"`
-	GinMode        string `json:"gin_mode" env:"gin_mode"`
-	Port           int    `json:"port" env:"port"`
+	GinMode        string `json:"gin_mode" env:"gin_mode" validate:"omitempty,oneof=debug release test"`
+	Port           int    `json:"port" env:"port" validate:"min=1,max=65535"`
 	EnvMode        string `json:"env_mode" env:"env_mode"`
 	LoggerLanguage string `json:"logger_lang" env:"logger_lang"`
 }
 
 // TokenConfig represents the configuration for token expiration times
 type TokenConfig struct {
-	Type                   string `json:"type"`                     // token类型
-	AccessTokenExpiration  string `json:"access_token_expiration"`  // 访问令牌的过期时间
-	RefreshTokenExpiration string `json:"refresh_token_expiration"` // 刷新令牌的过期时间
+	Type                   string             `json:"type"`                                                         // token类型
+	AccessTokenExpiration  string             `json:"access_token_expiration" reload:"true"`                        // 访问令牌的过期时间
+	RefreshTokenExpiration string             `json:"refresh_token_expiration" reload:"true"`                       // 刷新令牌的过期时间
+	Algorithm              string             `json:"algorithm" validate:"omitempty,oneof=HS256 RS256 ES256 EdDSA"` // 签名算法 HS256(默认), RS256, ES256, EdDSA
+	Keys                   []SigningKeyConfig `json:"keys" validate:"dive"`                                         // 签名密钥, 非对称算法必须配置, HS256 为空时使用 system.user.sign
+	RotationInterval       string             `json:"rotation_interval"`                                            // 自动生成新密钥的轮换周期, 为空时不轮换; 生成的密钥不保存, 只适用于单个实例
+	RotationGrace          string             `json:"rotation_grace"`                                               // 密钥停止签发后仍可用于校验的时间
+}
+
+// SigningKeyConfig 签名密钥, 通过 not_before/not_after 安排密钥的启用和退役
+type SigningKeyConfig struct {
+	ID        string `json:"kid"`                                                          // 密钥ID, 写入令牌头部的 kid
+	Algorithm string `json:"algorithm" validate:"omitempty,oneof=HS256 RS256 ES256 EdDSA"` // 为空时使用 TokenConfig.Algorithm
+	Path      string `json:"path" validate:"required"`                                     // PEM格式的私钥文件
+	NotBefore string `json:"not_before"`                                                   // 开始签发的时间, RFC3339
+	NotAfter  string `json:"not_after"`                                                    // 停止签发的时间, RFC3339
+}
+
+// PasswordConfig 密码哈希配置
+type PasswordConfig struct {
+	Algorithm       string `json:"algorithm" validate:"omitempty,oneof=argon2id bcrypt"` // argon2id(默认) 或 bcrypt
+	BcryptCost      int    `json:"bcrypt_cost" validate:"omitempty,min=4,max=31"`        // bcrypt cost
+	Argon2Memory    uint32 `json:"argon2_memory"`                                        // argon2 内存, 单位KiB
+	Argon2Time      uint32 `json:"argon2_time"`                                          // argon2 迭代次数
+	Argon2Threads   uint8  `json:"argon2_threads"`                                       // argon2 并行度
+	RejectPlaintext bool   `json:"reject_plaintext"`                                     // 拒绝历史遗留的明文密码
+}
+
+// OIDCProviderConfig 外部OIDC登录提供方
+type OIDCProviderConfig struct {
+	Name         string   `json:"name"`                                  // 显示名称
+	Issuer       string   `json:"issuer" validate:"omitempty,url"`       // 用于发现 /.well-known/openid-configuration
+	ClientID     string   `json:"client_id"`                             // 在提供方注册的客户端ID
+	ClientSecret string   `json:"client_secret" secret:"true"`           // 在提供方注册的客户端密钥
+	RedirectURL  string   `json:"redirect_url" validate:"omitempty,url"` // 回调地址, 指向 /api/v1/oauth2/callback/{provider}
+	Scopes       []string `json:"scopes"`                                // 额外的scope, openid 会自动加入
+	AutoSignup   bool     `json:"auto_signup"`                           // 没有匹配的账号时自动创建
+}
+
+// ProblemConfig RFC 7807 错误响应, 未开启时请求的 Accept 包含 application/problem+json 也会使用
+type ProblemConfig struct {
+	Enable   bool   `json:"enable"`    // 所有错误响应使用 Problem Details
+	TypeBase string `json:"type_base"` // type 的前缀, 为空时为 /errors
 }
 
+// RateLimitConfig 根路由的限流, 每个客户端IP一个令牌桶
+type RateLimitConfig struct {
+	QPS   float64 `json:"qps" validate:"gt=0" reload:"true"`   // 每秒补充的令牌数
+	Burst int     `json:"burst" validate:"gt=0" reload:"true"` // 令牌桶容量
+}
+
+// OIDCConfig 外部登录提供方, key 为路由中的提供方名称
+type OIDCConfig map[string]OIDCProviderConfig
+
 type Database struct {
 	Type     string `json:"type" env:"type"`
 	Username string `json:"username" env:"username"`
-	Password string `json:"password" env:"password"`
+	Password string `json:"password" env:"password" secret:"true"`
 	Host     string `json:"host" env:"host"`
-	Port     int    `json:"port" env:"port"`
+	Port     int    `json:"port" env:"port" validate:"omitempty,min=1,max=65535"`
 	DBName   string `json:"dbname" env:"dbname"`
 	Config   string `json:"config"`
 }
 
 type SystemUserConfig struct {
-	Sign string `json:"sign"`
+	Sign string `json:"sign" secret:"true"`
 }
 
 type SystemAdminConfig struct {
-	Sign string `json:"sign"`
+	Sign string `json:"sign" secret:"true"`
 }
 
 type SystemVersion struct {
@@ -58,12 +98,18 @@ type System struct {
 	Admin         SystemAdminConfig `json:"admin"`
 	Version       SystemVersion     `json:"version"`
 	Name          string            `json:"name" env:"app_name"`
-	Locale        string            `json:"locale" env:"locale"`
+	Locale        string            `json:"locale" env:"locale" validate:"required"`
 	HideVersion   bool              `json:"hide_version" env:"hide_version"`
 	Token         TokenConfig       `json:"token"`
+	Password      PasswordConfig    `json:"password"`
+	OIDC          OIDCConfig        `json:"oidc" validate:"dive"`
+	Problem       ProblemConfig     `json:"problem"`
+	RateLimit     RateLimitConfig   `json:"rate_limit"`
+	WatchInterval string            `json:"watch_interval"`              // 轮询配置文件变化的间隔, 如 10s, 为空时只在收到 SIGHUP 时重新加载
+	CursorSecret  string            `json:"cursor_secret" secret:"true"` // 游标分页的签名密钥, 为空时由 system.user.sign 派生
 	Static        string            `json:"static" env:"static_path"`
 	SwaggerEnable bool              `json:"swagger_enable" env:"swagger_enable"`
-	LoggerLever   string            `json:"logger_level" env:"logger_level"`
+	LoggerLever   string            `json:"logger_level" env:"logger_level" validate:"omitempty,oneof=debug info warn error dpanic panic fatal" reload:"true"`
 	LoggerPath    string            `json:"logger_path" env:"logger_path"`
 	LoggerName    string            `json:"logger_name" env:"logger_name"`
 }
@@ -75,87 +121,21 @@ type Configure struct {
 	System   System   `json:"system"`
 }
 
-func LoadBaseConfig() *BaseEnv {
-	fmt.Println("loading .env file")
-	env := LoadEnv()
-	baseEnv := &BaseEnv{}
-	if err := env.Bind(baseEnv); err != nil {
-		fmt.Printf("Error binding base env: %v\n", err)
-		return nil
-	}
-
-	// 如果指定了mode，加载对应的环境配置
-	if baseEnv.Mode != "" {
-		modeEnvData, err := os.ReadFile(".env." + baseEnv.Mode)
-		if err == nil {
-			env.Load(string(modeEnvData))
-			env.Bind(baseEnv)
-		}
-	}
-	return baseEnv
-}
-
-func LoadingConfigure() *Configure {
-	// 首先加载基础环境配置
-	baseEnv := LoadBaseConfig()
-	if baseEnv == nil {
-		fmt.Println("Failed to load base configuration")
-		return nil
-	}
-
-	// 使用ConfigurePath加载JSON配置
-	config := utils.LoadConfig[Configure](baseEnv.ConfigurePath)
-
-	// 重新加载环境变量以确保所有配置都被正确覆盖
-	env := LoadEnv()
-	if env != nil {
-		env.Bind(&config.Env)
-		env.Bind(&config.System)
-	}
-
-	// 保存合并后的配置
-	WriteConfigure(config, baseEnv.ConfigurePath)
-	return config
-}
-
-func WriteConfigure(config *Configure, configPath string) {
-	// 将配置结构体编码为 JSON 格式
-	jsonData, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		fmt.Printf("Error encoding config to JSON: %v", err)
-	}
-
-	// 打开或创建文件
-	file, err := os.OpenFile(configPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		fmt.Printf("Error opening or creating file: %v", err)
-	}
-	defer file.Close()
-
-	// 将 JSON 数据写入文件
-	if _, err := file.Write(jsonData); err != nil {
-		fmt.Printf("Error writing JSON data to file: %v", err)
-	}
-
-}
-
-func LoadEnv() *dotenv.DotENV {
-	data, err := os.ReadFile(".env")
-	if err != nil {
-		return nil
-	}
-	env := dotenv.New(string(data), "_.")
-	baseEnv := &BaseEnv{}
-
-	if err := env.Parse().Bind(baseEnv); err != nil {
-		return nil
-	}
-
-	if baseEnv.Mode != "" {
-		modeData, err := os.ReadFile(".env." + baseEnv.Mode)
-		if err == nil {
-			env.Load(string(modeData))
-		}
+// Default 默认配置, 是分层加载的第一层
+func Default() *Configure {
+	return &Configure{
+		Env: BaseEnv{
+			ConfigurePath:  DefaultConfigurePath,
+			GinMode:        "debug",
+			Port:           8080,
+			LoggerLanguage: "en",
+		},
+		System: System{
+			Name:       "Template",
+			Locale:     "locale",
+			LoggerPath: "logs",
+			LoggerName: "{app}-{level}-{date}.log",
+			RateLimit:  RateLimitConfig{QPS: 1, Burst: 32},
+		},
 	}
-	return env
 }
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"template/common/codec"
+	"text/tabwriter"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Mask 导出时代替敏感配置项的值
+const Mask = "******"
+
+// Redacted 返回带 secret:"true" 标签的配置项替换为 Mask 的副本
+func (c *Configure) Redacted() *Configure {
+	copied := redacted(*c)
+	return &copied
+}
+
+// redacted 返回 value 隐藏了敏感配置项的副本
+func redacted[T any](value T) T {
+	redact(reflect.ValueOf(&value).Elem())
+	return value
+}
+
+// redactedString 隐藏了敏感配置项的 JSON, 用于 String
+func redactedString[T any](value T) string {
+	data, err := json.Marshal(redacted(value))
+	if err != nil {
+		return fmt.Sprintf("!(%v)", err)
+	}
+	return string(data)
+}
+
+// marshalLogObject 按 json 标签写出隐藏了敏感配置项的字段, 用于 zap 日志
+func marshalLogObject[T any](enc zapcore.ObjectEncoder, value T) error {
+	copied := reflect.ValueOf(redacted(value))
+	errs := make([]error, 0, copied.NumField())
+	for i := 0; i < copied.NumField(); i++ {
+		sf := copied.Type().Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		errs = append(errs, enc.AddReflected(name, copied.Field(i).Interface()))
+	}
+	return errors.Join(errs...)
+}
+
+// 包含敏感配置项的类型在 fmt 和 zap 日志中隐藏敏感配置项
+
+func (c Configure) String() string { return redactedString(c) }
+
+func (c Configure) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalLogObject(enc, c)
+}
+
+func (d Database) String() string { return redactedString(d) }
+
+func (d Database) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalLogObject(enc, d)
+}
+
+func (s System) String() string { return redactedString(s) }
+
+func (s System) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalLogObject(enc, s)
+}
+
+func (u SystemUserConfig) String() string { return redactedString(u) }
+
+func (a SystemAdminConfig) String() string { return redactedString(a) }
+
+func (p OIDCProviderConfig) String() string { return redactedString(p) }
+
+// redact 替换敏感配置项, map 和切片先复制再修改, 不影响原配置
+func redact(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			sf := value.Type().Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			field := value.Field(i)
+			if sf.Tag.Get("secret") == "true" && field.Kind() == reflect.String {
+				if field.String() != "" {
+					field.SetString(Mask)
+				}
+				continue
+			}
+			redact(field)
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			return
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			redact(elem)
+			copied.SetMapIndex(iter.Key(), elem)
+		}
+		value.Set(copied)
+	case reflect.Slice:
+		if value.IsNil() {
+			return
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		reflect.Copy(copied, value)
+		for i := 0; i < copied.Len(); i++ {
+			redact(copied.Index(i))
+		}
+		value.Set(copied)
+	}
+}
+
+// Export 按 format 写出隐藏了敏感配置项的配置, 可以作为配置文件使用
+func Export(w io.Writer, conf *Configure, format codec.Format) error {
+	data, err := codec.Encode(format, conf.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ExportSources 按配置项路径写出隐藏了敏感配置项的值和来源
+func ExportSources(w io.Writer, conf *Configure, sources Sources) error {
+	values, err := readConfigure("", conf.Redacted())
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(values.values))
+	for path := range values.values {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, path := range paths {
+		value, err := json.Marshal(values.values[path])
+		if err != nil {
+			return err
+		}
+		source := sources.Of(path)
+		if source == "" {
+			source = SourceDefault
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", path, value, source)
+	}
+	return table.Flush()
+}
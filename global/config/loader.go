@@ -0,0 +1,416 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"template/common/codec"
+	"template/common/dotenv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultConfigurePath 默认的配置文件, 不存在时只使用默认值和环境变量
+const DefaultConfigurePath = "data/config.json"
+
+// EnvPrefix 系统环境变量的前缀, 如 APP_PORT, APP_DATABASE__PASSWORD
+const EnvPrefix = "APP_"
+
+// 配置项的来源, 另外文件为 file:<路径>, .env 为文件名, 环境变量为 env:<变量名>
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+)
+
+// Sources 每个配置项最终取值的来源, key 为配置项路径, 如 system.token.type
+type Sources map[string]string
+
+// Of 配置项的来源, path 是数组元素或对象的字段时返回所在配置项的来源
+func (s Sources) Of(path string) string {
+	for path != "" {
+		if source, ok := s[path]; ok {
+			return source
+		}
+		index := strings.LastIndex(path, ".")
+		if index < 0 {
+			break
+		}
+		path = path[:index]
+	}
+	return ""
+}
+
+// Flags 命令行参数, 优先级最高
+type Flags struct {
+	Config string
+	Mode   string
+	Set    []string // key=value, key 同环境变量名或配置项路径
+}
+
+// NewFlagSet 创建解析 -config, -mode 和 -set key=value 的 flag.FlagSet
+func NewFlagSet(name string) (*flag.FlagSet, *Flags) {
+	flags := new(Flags)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&flags.Config, "config", "", "配置文件路径")
+	fs.StringVar(&flags.Mode, "mode", "", "运行模式, 会加载 .env.<mode>")
+	fs.Func("set", "覆盖配置项, 如 -set system.token.type=Bearer, 可以重复使用", func(value string) error {
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("expect key=value, got %q", value)
+		}
+		flags.Set = append(flags.Set, value)
+		return nil
+	})
+	return fs, flags
+}
+
+// layer 一层配置, key 为配置项路径
+type layer struct {
+	source string
+	values map[string]any
+	// origins 与 source 不同的来源, 如环境变量层中每个配置项对应的变量名
+	origins map[string]string
+}
+
+func (l *layer) sourceOf(path string) string {
+	if origin, ok := l.origins[path]; ok {
+		return origin
+	}
+	return l.source
+}
+
+func (l *layer) get(path string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	value, ok := l.values[path]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+type loader struct {
+	dir       string
+	environ   []string
+	flags     *Flags
+	masterKey []byte
+}
+
+type LoadOption func(loader *loader)
+
+// WithDir .env 和相对路径配置文件所在的目录, 默认为当前目录
+func WithDir(dir string) LoadOption {
+	return func(loader *loader) {
+		loader.dir = dir
+	}
+}
+
+// WithEnviron 代替 os.Environ() 的环境变量, 格式为 KEY=value
+func WithEnviron(environ []string) LoadOption {
+	return func(loader *loader) {
+		loader.environ = environ
+	}
+}
+
+// WithFlags 命令行参数
+func WithFlags(flags *Flags) LoadOption {
+	return func(loader *loader) {
+		if flags != nil {
+			loader.flags = flags
+		}
+	}
+}
+
+// Load 按 默认值 → 配置文件 (JSON、YAML 或 TOML) → .env → .env.<mode> → 环境变量 → 命令行参数 的顺序加载配置,
+// 后面的覆盖前面的, 并校验 validate 标签
+//
+// 配置文件路径和 mode 也可以在后面几层中设置; 敏感配置项中的密钥引用在合并后解析;
+// 不会再写回配置文件, 需要时使用 Export
+func Load(opts ...LoadOption) (*Configure, Sources, error) {
+	l := &loader{dir: ".", environ: os.Environ(), flags: new(Flags)}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	defaults, err := readConfigure(SourceDefault, Default())
+	if err != nil {
+		return nil, nil, err
+	}
+	dotEnv, err := l.readDotEnv(".env")
+	if err != nil {
+		return nil, nil, err
+	}
+	environ, err := l.readEnviron()
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, err := l.readFlags()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mode, _ := lookup("env.mode", defaults, dotEnv, environ, flags)
+	var modeEnv *layer
+	if mode != "" {
+		if modeEnv, err = l.readDotEnv(".env." + mode); err != nil {
+			return nil, nil, err
+		}
+	}
+	path, explicit := lookup("env.config", dotEnv, modeEnv, environ, flags)
+	if !explicit {
+		path, _ = defaults.get("env.config")
+	}
+	file, err := l.readFile(path, explicit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree := make(map[string]any)
+	sources := make(Sources)
+	for _, layer := range []*layer{defaults, file, dotEnv, modeEnv, environ, flags} {
+		if layer == nil {
+			continue
+		}
+		for path, value := range layer.values {
+			setPath(tree, path, value)
+			sources[path] = layer.sourceOf(path)
+		}
+	}
+
+	if err := l.resolveSecrets(tree, sources); err != nil {
+		return nil, nil, err
+	}
+	conf, err := decode(tree, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validate(conf, sources); err != nil {
+		return nil, nil, err
+	}
+	return conf, sources, nil
+}
+
+// lookup 后面的层优先
+func lookup(path string, layers ...*layer) (string, bool) {
+	for index := len(layers) - 1; index >= 0; index-- {
+		if value, ok := layers[index].get(path); ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (l *loader) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(l.dir, name)
+}
+
+func readConfigure(source string, conf *Configure) (*layer, error) {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	var node map[string]any
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	if err := configSchema.flatten(node, "", values); err != nil {
+		return nil, err
+	}
+	return &layer{source: source, values: values}, nil
+}
+
+// readFile 按扩展名读取 JSON、YAML 或 TOML 配置文件, 没有显式指定的文件不存在时跳过
+func (l *loader) readFile(name string, explicit bool) (*layer, error) {
+	format, err := codec.FormatOf(name)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", name, err)
+	}
+	data, err := os.ReadFile(l.path(name))
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s: %w", name, err)
+	}
+	var node map[string]any
+	if err := codec.Decode(format, data, &node); err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", name, err)
+	}
+	values := make(map[string]any)
+	if err := configSchema.flatten(node, "", values); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", name, err)
+	}
+	return &layer{source: "file:" + name, values: values}, nil
+}
+
+// readDotEnv 读取 .env 文件, 文件不存在时跳过
+func (l *loader) readDotEnv(name string) (*layer, error) {
+	data, err := os.ReadFile(l.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s: %w", name, err)
+	}
+	env := dotenv.New(string(data), "_.")
+	result := &layer{source: name, values: make(map[string]any)}
+	for key, value := range env.Parse().Values() {
+		path, ok := configSchema.resolve(key)
+		if !ok {
+			return nil, fmt.Errorf("config: %s: unknown key %q", name, key)
+		}
+		if err := result.set(path, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// readEnviron 读取 EnvPrefix 开头的环境变量, 忽略不是配置项的变量
+func (l *loader) readEnviron() (*layer, error) {
+	result := &layer{source: "env", values: make(map[string]any), origins: make(map[string]string)}
+	for _, entry := range l.environ {
+		key, value, _ := strings.Cut(entry, "=")
+		name, ok := strings.CutPrefix(key, EnvPrefix)
+		if !ok {
+			continue
+		}
+		path, ok := configSchema.resolve(name)
+		if !ok {
+			continue
+		}
+		result.origins[path] = "env:" + key
+		if err := result.set(path, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (l *loader) readFlags() (*layer, error) {
+	result := &layer{source: SourceFlag, values: make(map[string]any)}
+	if l.flags.Config != "" {
+		result.values["env.config"] = l.flags.Config
+	}
+	if l.flags.Mode != "" {
+		result.values["env.mode"] = l.flags.Mode
+	}
+	for _, entry := range l.flags.Set {
+		key, value, _ := strings.Cut(entry, "=")
+		path, ok := configSchema.resolve(key)
+		if !ok {
+			return nil, fmt.Errorf("config: -set: unknown key %q", key)
+		}
+		if err := result.set(path, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// set 按配置项的类型转换后保存
+func (l *layer) set(path string, value any) error {
+	f, _ := configSchema.leaf(path)
+	converted, err := f.convert(value)
+	if err != nil {
+		return fmt.Errorf("config: %s: invalid value for %s: %w", l.sourceOf(path), path, err)
+	}
+	l.values[path] = converted
+	return nil
+}
+
+// setPath 按路径写入嵌套的 map, 路径上不是对象的值会被替换
+func setPath(tree map[string]any, path string, value any) {
+	segments := strings.Split(path, ".")
+	current := tree
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := current[segment].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			current[segment] = next
+		}
+		current = next
+	}
+	current[segments[len(segments)-1]] = value
+}
+
+func decode(tree map[string]any, sources Sources) (*Configure, error) {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	conf := new(Configure)
+	if err := decoder.Decode(conf); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return nil, fmt.Errorf("config: %s (from %s): expect %s, got %s",
+				typeError.Field, sources.Of(typeError.Field), typeError.Type, typeError.Value)
+		}
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return conf, nil
+}
+
+var configValidator = newValidator()
+
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(sf reflect.StructField) string {
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return validate
+}
+
+// Validate 按 validate 标签校验配置
+func (c *Configure) Validate() error {
+	return validate(c, nil)
+}
+
+func validate(conf *Configure, sources Sources) error {
+	err := configValidator.Struct(conf)
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+	errs := make([]error, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		path := fieldPath(fieldError.Namespace())
+		rule := fieldError.Tag()
+		if fieldError.Param() != "" {
+			rule += "=" + fieldError.Param()
+		}
+		value := fmt.Sprintf("%q", fmt.Sprint(fieldError.Value()))
+		if f, ok := configSchema.leaf(path); ok && f.secret {
+			value = Mask
+		}
+		message := fmt.Sprintf("config: %s = %s does not satisfy %s", path, value, rule)
+		if source := sources.Of(path); source != "" {
+			message += " (from " + source + ")"
+		}
+		errs = append(errs, errors.New(message))
+	}
+	return errors.Join(errs...)
+}
+
+// fieldPath 将 Configure.system.oidc[google].issuer 转换为 system.oidc.google.issuer
+func fieldPath(namespace string) string {
+	_, path, _ := strings.Cut(namespace, ".")
+	path = strings.ReplaceAll(path, "[", ".")
+	return strings.ReplaceAll(path, "]", "")
+}
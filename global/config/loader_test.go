@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"template/common/codec"
+	"testing"
+)
+
+func testConfigure() *Configure {
+	conf := Default()
+	conf.Database = Database{Type: "postgres", Username: "app", Password: "123", Host: "db", Port: 5432, DBName: "true"}
+	conf.System.Language = "zh-CN"
+	conf.System.User.Sign = "null"
+	conf.System.Token.Type = "Bearer"
+	conf.System.Token.Keys = []SigningKeyConfig{{ID: "yes", Algorithm: "RS256", Path: "keys/2024.pem"}}
+	conf.System.RateLimit = RateLimitConfig{QPS: 2.5, Burst: 10}
+	conf.System.OIDC = OIDCConfig{
+		"google": {
+			Issuer:      "https://accounts.google.com",
+			ClientID:    "0123",
+			RedirectURL: "http://localhost:8080/api/v1/oauth2/callback/google",
+			Scopes:      []string{"email", "profile"},
+		},
+	}
+	return conf
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	for _, name := range []string{"config.json", "config.yaml", "config.yml", "config.toml"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			want := testConfigure()
+			want.Env.ConfigurePath = name
+			format, _ := codec.FormatOf(name)
+			data, err := codec.Encode(format, want)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			writeFile(t, dir, name, data)
+
+			got, sources, err := Load(WithDir(dir), WithEnviron(nil), WithFlags(&Flags{Config: name}))
+			if err != nil {
+				t.Fatalf("Load: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("loaded configure differs:\n got %+v\nwant %+v\n%s", got, want, data)
+			}
+			if source := sources.Of("system.oidc.google.client_id"); source != "file:"+name {
+				t.Errorf("source of client_id = %q, want file:%s", source, name)
+			}
+			if source := sources.Of("env.config"); source != SourceFlag {
+				t.Errorf("source of env.config = %q, want %s", source, SourceFlag)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	l := &loader{dir: dir}
+
+	// 没有显式指定的配置文件不存在时跳过
+	if file, err := l.readFile(DefaultConfigurePath, false); err != nil || file != nil {
+		t.Fatalf("missing default file = %v, %v", file, err)
+	}
+	if _, err := l.readFile("missing.yaml", true); err == nil || !strings.Contains(err.Error(), "config: read missing.yaml") {
+		t.Fatalf("missing explicit file: err = %v", err)
+	}
+	if _, err := l.readFile("config.ini", true); !errors.Is(err, codec.ErrUnknownFormat) {
+		t.Fatalf("unknown format: err = %v, want %v", err, codec.ErrUnknownFormat)
+	}
+
+	writeFile(t, dir, "unknown.toml", []byte("[system]\nunknown = 1\n"))
+	if _, err := l.readFile("unknown.toml", true); err == nil || !strings.Contains(err.Error(), `unknown key "system.unknown"`) {
+		t.Fatalf("unknown key: err = %v", err)
+	}
+	writeFile(t, dir, "invalid.yaml", []byte("system: [unclosed"))
+	if _, err := l.readFile("invalid.yaml", true); err == nil || !strings.Contains(err.Error(), "config: parse invalid.yaml") {
+		t.Fatalf("invalid file: err = %v", err)
+	}
+	writeFile(t, dir, "branch.yaml", []byte("system: text\n"))
+	if _, err := l.readFile("branch.yaml", true); err == nil || !strings.Contains(err.Error(), "system must be an object") {
+		t.Fatalf("scalar branch: err = %v", err)
+	}
+
+	writeFile(t, dir, "partial.yaml", []byte("system:\n  token:\n    type: \"true\"\n  oidc:\n    github:\n      scopes: [user]\ndatabase: ~\n"))
+	file, err := l.readFile("partial.yaml", true)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	want := map[string]any{
+		"system.token.type":         "true",
+		"system.oidc.github.scopes": []any{"user"},
+	}
+	if !reflect.DeepEqual(file.values, want) || file.source != "file:partial.yaml" {
+		t.Fatalf("layer = %s %v, want %v", file.source, file.values, want)
+	}
+}
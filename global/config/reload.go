@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotReloadable 修改了不能在运行中重新加载的配置项, 需要重启后生效
+var ErrNotReloadable = errors.New("config: keys cannot be reloaded without restart")
+
+// Subscriber 新的配置生效后调用, old 和 next 都不能修改
+type Subscriber func(old, next *Configure) error
+
+// Reloader 在运行中重新加载配置
+//
+// 新的配置通过校验且只修改了带 reload:"true" 标签的配置项时才会整体替换, 然后依次通知订阅者;
+// App.Config 不随之更新, 需要重新加载的配置通过 Current 或 Subscribe 获取
+type Reloader struct {
+	mu          sync.Mutex
+	current     atomic.Pointer[Configure]
+	opts        []LoadOption
+	subscribers []Subscriber
+}
+
+// NewReloader conf 为当前配置, opts 与加载 conf 时相同
+func NewReloader(conf *Configure, opts ...LoadOption) *Reloader {
+	reloader := &Reloader{opts: opts}
+	reloader.current.Store(conf)
+	return reloader
+}
+
+// Current 当前生效的配置
+func (r *Reloader) Current() *Configure {
+	return r.current.Load()
+}
+
+// Subscribe 添加订阅者, 按添加的顺序通知
+func (r *Reloader) Subscribe(subscriber Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, subscriber)
+}
+
+// Reload 重新分层加载配置, 返回修改了的配置项路径, 没有修改时不通知订阅者
+//
+// 加载或校验失败、修改了不能重新加载的配置项时保留当前配置;
+// 订阅者的错误合并后返回, 不影响其他订阅者
+func (r *Reloader) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, _, err := Load(r.opts...)
+	if err != nil {
+		return nil, err
+	}
+	old := r.Current()
+	changed, err := diff(old, next)
+	if err != nil || len(changed) == 0 {
+		return nil, err
+	}
+	rejected := make([]string, 0)
+	for _, path := range changed {
+		if f, ok := configSchema.leaf(path); !ok || !f.reload {
+			rejected = append(rejected, path)
+		}
+	}
+	if len(rejected) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotReloadable, strings.Join(rejected, ", "))
+	}
+
+	r.current.Store(next)
+	errs := make([]error, 0)
+	for _, subscriber := range r.subscribers {
+		if err := subscriber(old, next); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+// diff 值不同的配置项路径
+func diff(old, next *Configure) ([]string, error) {
+	before, err := readConfigure("", old)
+	if err != nil {
+		return nil, err
+	}
+	after, err := readConfigure("", next)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0)
+	for path, value := range after.values {
+		if !reflect.DeepEqual(before.values[path], value) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before.values {
+		if _, ok := after.values[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
+
+// fileStamp 判断文件是否修改
+type fileStamp struct {
+	exist   bool
+	size    int64
+	modTime time.Time
+}
+
+// files 当前配置使用的配置文件、.env 和 .env.<mode>
+func (r *Reloader) files() []string {
+	l := &loader{dir: "."}
+	for _, opt := range r.opts {
+		opt(l)
+	}
+	conf := r.Current()
+	files := []string{l.path(conf.Env.ConfigurePath), l.path(".env")}
+	if conf.Env.Mode != "" {
+		files = append(files, l.path(".env."+conf.Env.Mode))
+	}
+	return files
+}
+
+func (r *Reloader) stamps() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, file := range r.files() {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{exist: true, size: info.Size(), modTime: info.ModTime()}
+		} else {
+			stamps[file] = fileStamp{}
+		}
+	}
+	return stamps
+}
+
+// Watch 每隔 interval 检查配置文件和 .env 文件, 有变化时调用 Reload 并将结果交给 onReload, 直到 ctx 结束
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onReload func(changed []string, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stamps := r.stamps()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := r.stamps()
+			if maps.Equal(stamps, current) {
+				continue
+			}
+			stamps = current
+			onReload(r.Reload())
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// field 叶子配置项
+type field struct {
+	typ    reflect.Type
+	secret bool // 带 secret:"true" 标签, 导出和校验错误中不显示
+	reload bool // 带 reload:"true" 标签, 可以在运行中重新加载
+}
+
+// schema 由 Configure 的 json 标签得到的配置项路径, 如 system.token.type
+//
+// map 类型的配置项 (如 system.oidc) 的 key 在路径中用 * 表示
+type schema struct {
+	leaves   map[string]field
+	branches map[string]bool
+	maps     map[string]bool
+	// aliases 兼容旧 .env 的变量名 (env 标签), 只包括 env 和 system 下的配置项
+	aliases map[string]string
+}
+
+var configSchema = newSchema(reflect.TypeOf(Configure{}))
+
+func newSchema(t reflect.Type) *schema {
+	s := &schema{
+		leaves:   make(map[string]field),
+		branches: make(map[string]bool),
+		maps:     make(map[string]bool),
+		aliases:  make(map[string]string),
+	}
+	s.describe(t, "")
+	return s
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func (s *schema) describe(t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		path := joinPath(prefix, name)
+		switch {
+		case sf.Type.Kind() == reflect.Struct:
+			s.branches[path] = true
+			s.describe(sf.Type, path)
+		case sf.Type.Kind() == reflect.Map && sf.Type.Elem().Kind() == reflect.Struct:
+			s.branches[path] = true
+			s.maps[path] = true
+			s.branches[path+".*"] = true
+			s.describe(sf.Type.Elem(), path+".*")
+		default:
+			s.leaves[path] = field{
+				typ:    sf.Type,
+				secret: sf.Tag.Get("secret") == "true",
+				reload: sf.Tag.Get("reload") == "true",
+			}
+			env := sf.Tag.Get("env")
+			if env != "" && (strings.HasPrefix(path, "env.") || strings.HasPrefix(path, "system.")) {
+				s.aliases[env] = path
+			}
+		}
+	}
+}
+
+// pattern 将 map 的 key 替换为 *
+func (s *schema) pattern(path string) string {
+	current := ""
+	for _, segment := range strings.Split(path, ".") {
+		if s.maps[current] {
+			segment = "*"
+		}
+		current = joinPath(current, segment)
+	}
+	return current
+}
+
+func (s *schema) leaf(path string) (field, bool) {
+	f, ok := s.leaves[s.pattern(path)]
+	return f, ok
+}
+
+func (s *schema) branch(path string) bool {
+	return s.branches[s.pattern(path)]
+}
+
+// resolve 将变量名转换为配置项路径
+//
+// 支持旧 .env 的变量名 (如 app_name)、json 路径 (如 system.token.type)
+// 和用 __ 分隔的路径 (如 SYSTEM__TOKEN__TYPE), 不区分大小写
+func (s *schema) resolve(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if path, ok := s.aliases[name]; ok {
+		return path, true
+	}
+	path := strings.ReplaceAll(name, "__", ".")
+	_, ok := s.leaf(path)
+	return path, ok
+}
+
+// flatten 将 JSON 对象展开为 路径 → 值, 出现未知的配置项时返回错误
+func (s *schema) flatten(node map[string]any, prefix string, values map[string]any) error {
+	for key, value := range node {
+		path := joinPath(prefix, key)
+		if s.branch(path) {
+			if value == nil {
+				continue
+			}
+			child, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s must be an object", path)
+			}
+			if err := s.flatten(child, path, values); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := s.leaf(path); !ok {
+			return fmt.Errorf("unknown key %q", path)
+		}
+		values[path] = value
+	}
+	return nil
+}
+
+// convert 将 .env、环境变量和命令行中的值转换为配置项的类型
+//
+// 字符串数组可以用逗号分隔, 对象和其他数组使用 JSON
+func (f field) convert(value any) (any, error) {
+	text, isText := value.(string)
+	if !isText {
+		if f.typ.Kind() != reflect.Slice && f.typ.Kind() != reflect.Map && f.typ.Kind() != reflect.Struct {
+			text = fmt.Sprint(value)
+		}
+	}
+	text = strings.TrimSpace(text)
+	switch f.typ.Kind() {
+	case reflect.String:
+		if isText {
+			return value, nil
+		}
+		return text, nil
+	case reflect.Bool:
+		return strconv.ParseBool(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(text, 10, f.typ.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(text, 10, f.typ.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(text, f.typ.Bits())
+	}
+	if !isText {
+		return value, nil
+	}
+	if f.typ.Kind() == reflect.Slice && f.typ.Elem().Kind() == reflect.String && !strings.HasPrefix(text, "[") {
+		items := make([]any, 0)
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// 敏感配置项 (带 secret:"true" 标签) 的值可以引用其他位置的密钥, 在加载时解析:
+//
+//	file:///run/secrets/db_pw  读取文件内容, 去掉末尾的换行, 相对路径以 WithDir 为准
+//	env:DB_PASS                读取环境变量
+//	base64:c2VjcmV0            base64 解码
+//	enc:...                    使用主密钥解密 Encrypt 的结果
+const (
+	secretFile   = "file://"
+	secretEnv    = "env:"
+	secretBase64 = "base64:"
+	secretEnc    = "enc:"
+)
+
+// MasterKeyEnv 主密钥所在的环境变量, 值为 base64 编码的 16、24 或 32 字节 AES 密钥
+const MasterKeyEnv = "APP_MASTER_KEY"
+
+// ErrMasterKey 解密或加密 enc: 值时没有主密钥
+var ErrMasterKey = errors.New("config: master key is not set, set " + MasterKeyEnv)
+
+// WithMasterKey 解密 enc: 使用的主密钥, 默认读取 MasterKeyEnv
+func WithMasterKey(key []byte) LoadOption {
+	return func(loader *loader) {
+		loader.masterKey = key
+	}
+}
+
+// ParseMasterKey 解析 base64 编码的主密钥
+func ParseMasterKey(text string) ([]byte, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrMasterKey
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid master key: %w", err)
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, fmt.Errorf("config: invalid master key: %w", err)
+	}
+	return key, nil
+}
+
+// Encrypt 使用主密钥加密, 返回可以写入配置的 enc: 值
+func Encrypt(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return secretEnc + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果
+func Decrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretEnc))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("decrypt failed, wrong master key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD AES-GCM
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// getenv 从 WithEnviron 或 os.Environ() 中读取环境变量
+func (l *loader) getenv(name string) (string, bool) {
+	for _, entry := range slices.Backward(l.environ) {
+		if key, value, ok := strings.Cut(entry, "="); ok && key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (l *loader) key() ([]byte, error) {
+	if l.masterKey != nil {
+		return l.masterKey, nil
+	}
+	text, _ := l.getenv(MasterKeyEnv)
+	return ParseMasterKey(text)
+}
+
+// resolveSecret 解析密钥引用, 不是引用时原样返回
+func (l *loader) resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretFile):
+		data, err := os.ReadFile(l.path(strings.TrimPrefix(value, secretFile)))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, secretEnv):
+		name := strings.TrimPrefix(value, secretEnv)
+		secret, ok := l.getenv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, secretBase64):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretBase64))
+		if err != nil {
+			return "", err
+		}
+		return string(decoded), nil
+	case strings.HasPrefix(value, secretEnc):
+		key, err := l.key()
+		if err != nil {
+			return "", err
+		}
+		return Decrypt(key, value)
+	}
+	return value, nil
+}
+
+// resolveSecrets 解析合并后所有敏感配置项中的密钥引用
+//
+// 错误信息只包含配置项路径和来源, 不包含值; 值为 Mask 说明使用了导出的配置, 需要填写真实的值
+func (l *loader) resolveSecrets(tree map[string]any, sources Sources) error {
+	paths := make([]string, 0)
+	for path := range sources {
+		if f, ok := configSchema.leaf(path); ok && f.secret {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	errs := make([]error, 0)
+	for _, path := range paths {
+		value, ok := getPath(tree, path).(string)
+		if !ok || value == "" {
+			continue
+		}
+		if value == Mask {
+			errs = append(errs, fmt.Errorf("config: %s (from %s) is masked, set the real value or a secret reference", path, sources.Of(path)))
+			continue
+		}
+		resolved, err := l.resolveSecret(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: %s (from %s): %w", path, sources.Of(path), err))
+			continue
+		}
+		setPath(tree, path, resolved)
+	}
+	return errors.Join(errs...)
+}
+
+// getPath 按路径读取嵌套的 map
+func getPath(tree map[string]any, path string) any {
+	segments := strings.Split(path, ".")
+	current := tree
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := current[segment].(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current[segments[len(segments)-1]]
+}
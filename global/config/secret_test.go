@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"template/common/codec"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var testMasterKey = bytes.Repeat([]byte{7}, 32)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "db_pw", []byte("from-file\r\n"))
+	encrypted, err := Encrypt(testMasterKey, "from-enc")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	otherKey, _ := Encrypt(bytes.Repeat([]byte{8}, 32), "from-enc")
+
+	l := &loader{dir: dir, environ: []string{"DB_PASS=old", "DB_PASS=from-env", "EMPTY="}, masterKey: testMasterKey}
+	tests := map[string]struct {
+		value string
+		want  string
+	}{
+		"plain":         {"secret", "secret"},
+		"relative file": {"file://db_pw", "from-file"},
+		"absolute file": {"file://" + filepath.Join(dir, "db_pw"), "from-file"},
+		"env":           {"env:DB_PASS", "from-env"},
+		"empty env":     {"env:EMPTY", ""},
+		"base64":        {"base64:" + base64.StdEncoding.EncodeToString([]byte("from-base64")), "from-base64"},
+		"enc":           {encrypted, "from-enc"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := l.resolveSecret(test.value)
+			if err != nil {
+				t.Fatalf("resolveSecret(%q): %v", test.value, err)
+			}
+			if got != test.want {
+				t.Fatalf("resolveSecret(%q) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+
+	failures := map[string]string{
+		"missing file":  "file://missing",
+		"missing env":   "env:MISSING",
+		"bad base64":    "base64:!!!",
+		"wrong key":     otherKey,
+		"corrupted enc": encrypted[:len(encrypted)-4] + "AAAA",
+		"short enc":     "enc:AAAA",
+	}
+	for name, value := range failures {
+		t.Run(name, func(t *testing.T) {
+			if got, err := l.resolveSecret(value); err == nil {
+				t.Fatalf("resolveSecret(%q) = %q, want an error", value, got)
+			}
+		})
+	}
+
+	noKey := &loader{dir: dir}
+	if _, err := noKey.resolveSecret(encrypted); !errors.Is(err, ErrMasterKey) {
+		t.Fatalf("enc without master key: err = %v, want %v", err, ErrMasterKey)
+	}
+	fromEnv := &loader{dir: dir, environ: []string{MasterKeyEnv + "=" + base64.StdEncoding.EncodeToString(testMasterKey)}}
+	if got, err := fromEnv.resolveSecret(encrypted); err != nil || got != "from-enc" {
+		t.Fatalf("enc with %s = %q, %v", MasterKeyEnv, got, err)
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	tests := map[string]struct {
+		text string
+		ok   bool
+	}{
+		"aes-128":      {base64.StdEncoding.EncodeToString(make([]byte, 16)), true},
+		"aes-256":      {" " + base64.StdEncoding.EncodeToString(make([]byte, 32)) + "\n", true},
+		"empty":        {"", false},
+		"not base64":   {"!!!", false},
+		"wrong length": {base64.StdEncoding.EncodeToString(make([]byte, 10)), false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseMasterKey(test.text)
+			if (err == nil) != test.ok {
+				t.Fatalf("ParseMasterKey(%q) err = %v, want ok = %v", test.text, err, test.ok)
+			}
+		})
+	}
+}
+
+func TestLoadResolvesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "sign", []byte("file-sign\n"))
+	encrypted, err := Encrypt(testMasterKey, "client-secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	writeFile(t, dir, "config.yaml", []byte(fmt.Sprintf(`database:
+  host: env:DB_HOST
+  password: env:DB_PASS
+system:
+  user:
+    sign: file://sign
+  oidc:
+    google:
+      client_id: app
+      client_secret: %s
+`, encrypted)))
+
+	conf, _, err := Load(
+		WithDir(dir),
+		WithEnviron([]string{"DB_PASS=env-password"}),
+		WithFlags(&Flags{Config: "config.yaml"}),
+		WithMasterKey(testMasterKey),
+	)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if conf.Database.Password != "env-password" {
+		t.Errorf("database.password = %q", conf.Database.Password)
+	}
+	if conf.System.User.Sign != "file-sign" {
+		t.Errorf("system.user.sign = %q", conf.System.User.Sign)
+	}
+	if secret := conf.System.OIDC["google"].ClientSecret; secret != "client-secret" {
+		t.Errorf("client_secret = %q", secret)
+	}
+	// 只解析敏感配置项
+	if conf.Database.Host != "env:DB_HOST" {
+		t.Errorf("database.host = %q, want the reference unchanged", conf.Database.Host)
+	}
+}
+
+func TestLoadSecretErrors(t *testing.T) {
+	tests := map[string]struct {
+		password string
+		message  string
+	}{
+		"masked":        {Mask, "database.password (from file:config.yaml) is masked"},
+		"missing env":   {"env:MISSING", "database.password (from file:config.yaml): environment variable MISSING is not set"},
+		"no master key": {"enc:AAAA", "database.password (from file:config.yaml): " + ErrMasterKey.Error()},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "config.yaml", []byte("database:\n  password: \""+test.password+"\"\n"))
+			_, _, err := Load(WithDir(dir), WithEnviron(nil), WithFlags(&Flags{Config: "config.yaml"}))
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Fatalf("Load err = %v, want %q", err, test.message)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	conf := testConfigure()
+	conf.Database.Password = "db-password"
+	conf.System.User.Sign = "user-sign"
+	conf.System.Admin.Sign = "admin-sign"
+	conf.System.OIDC["google"] = OIDCProviderConfig{ClientID: "0123", ClientSecret: "client-secret"}
+	secrets := []string{conf.Database.Password, conf.System.User.Sign, conf.System.Admin.Sign, "client-secret"}
+
+	redacted := conf.Redacted()
+	if redacted.Database.Password != Mask || redacted.System.User.Sign != Mask || redacted.System.OIDC["google"].ClientSecret != Mask {
+		t.Fatalf("Redacted did not mask secrets: %+v", redacted)
+	}
+	if redacted.Database.Username != conf.Database.Username || redacted.System.OIDC["google"].ClientID != "0123" {
+		t.Fatal("Redacted changed values that are not secret")
+	}
+	if conf.Database.Password != "db-password" || conf.System.OIDC["google"].ClientSecret != "client-secret" {
+		t.Fatal("Redacted modified the original configure")
+	}
+	if empty := (&Configure{}).Redacted(); empty.Database.Password != "" {
+		t.Fatalf("empty secret = %q, want empty", empty.Database.Password)
+	}
+
+	var exported bytes.Buffer
+	if err := Export(&exported, conf, codec.YAML); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var sources bytes.Buffer
+	if err := ExportSources(&sources, conf, nil); err != nil {
+		t.Fatalf("ExportSources: %v", err)
+	}
+	logged := zapcore.NewMapObjectEncoder()
+	if err := conf.MarshalLogObject(logged); err != nil {
+		t.Fatalf("MarshalLogObject: %v", err)
+	}
+	outputs := map[string]string{
+		"String":           conf.String(),
+		"%v":               fmt.Sprintf("%v", conf.Database),
+		"%+v":              fmt.Sprintf("%+v", conf.System.OIDC["google"]),
+		"Export":           exported.String(),
+		"ExportSources":    sources.String(),
+		"MarshalLogObject": fmt.Sprint(logged.Fields),
+	}
+	for name, output := range outputs {
+		if !strings.Contains(output, Mask) {
+			t.Errorf("%s does not contain the mask: %s", name, output)
+		}
+		for _, secret := range secrets {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s leaks %q: %s", name, secret, output)
+			}
+		}
+	}
+}
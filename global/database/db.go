@@ -43,9 +43,13 @@ func CreateConnect(config config.Database) *gorm.DB {
 		}
 		result = db
 	}
+	if result == nil {
+		return nil
+	}
 	db,err := result.DB()
 	if err != nil {
 		// log.Fatalf("failed to get *sql.DB object: %v", err)
+		return nil
 	}
 
 	// 设置数据库连接池参数
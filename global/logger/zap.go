@@ -12,11 +12,39 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func NewLogger(config config.System) *zap.Logger {
+// Level NewLogger 创建的日志共用的最低级别, 可以在运行中修改
+var Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+// SetLevel 修改日志的最低级别, 为空时为 debug
+func SetLevel(text string) error {
+	if text == "" {
+		Level.SetLevel(zapcore.DebugLevel)
+		return nil
+	}
+	level, err := zapcore.ParseLevel(text)
+	if err != nil {
+		return err
+	}
+	Level.SetLevel(level)
+	return nil
+}
+
+// enabled 日志文件本身的级别和 Level 都满足时写入
+func enabled(minimum zapcore.Level) zap.LevelEnablerFunc {
+	return func(level zapcore.Level) bool {
+		return level >= minimum && Level.Enabled(level)
+	}
+}
+
+// NewLogger 按配置创建写入日志文件的 Logger, 日志级别无效或无法创建日志目录时返回错误
+func NewLogger(config config.System) (*zap.Logger, error) {
+	if err := SetLevel(config.LoggerLever); err != nil {
+		return nil, fmt.Errorf("logger: invalid level %q: %w", config.LoggerLever, err)
+	}
+
 	// 确保日志目录存在
 	if err := os.MkdirAll(config.LoggerPath, os.ModePerm); err != nil {
-		fmt.Printf("无法创建日志目录: %v", err)
-		return nil
+		return nil, fmt.Errorf("logger: create directory: %w", err)
 	}
 
 	appName := config.Name
@@ -86,19 +114,19 @@ func NewLogger(config config.System) *zap.Logger {
 	debugCore := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig),
 		zapcore.AddSync(debugFile),
-		zapcore.DebugLevel, // Debug级别
+		enabled(zapcore.DebugLevel), // Debug级别
 	)
 
 	infoCore := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig),
 		zapcore.AddSync(infoFile),
-		zapcore.InfoLevel, // Info级别
+		enabled(zapcore.InfoLevel), // Info级别
 	)
 
 	errorCore := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig),
 		zapcore.AddSync(errorFile),
-		zapcore.ErrorLevel, // Error级别
+		enabled(zapcore.ErrorLevel), // Error级别
 	)
 
 	// 使用Tee将多个Core合并
@@ -107,5 +135,5 @@ func NewLogger(config config.System) *zap.Logger {
 	// 创建Logger
 	logger := zap.New(core)
 
-	return logger
+	return logger, nil
 }
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+type localeContextKey struct{}
+
+// localeAliases 没有对应语言文件的常用地区
+var localeAliases = map[string]string{
+	"zh-hans": LanguageZH,
+	"zh-sg":   LanguageZH,
+	"zh-hant": LanguageTW,
+	"zh-hk":   LanguageTW,
+	"zh-mo":   LanguageTW,
+}
+
+// WithLocale 将本次请求使用的语言写入 context.Context
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// LocaleFromContext 读取本次请求使用的语言, 没有时返回空字符串
+func LocaleFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	locale, _ := ctx.Value(localeContextKey{}).(string)
+	return locale
+}
+
+// Languages 已加载语言文件的语言
+func Languages() []string {
+	return slices.Clone(getLanguages)
+}
+
+// Match 将客户端的语言标签匹配到已加载的语言, 如 zh_hk 匹配 zh-TW, en-US 匹配 en
+//
+// 依次去掉标签末尾的子标签查找, 都没有时使用主语言相同的第一个语言
+func Match(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return "", false
+	}
+	for prefix := tag; prefix != ""; {
+		if alias, ok := localeAliases[prefix]; ok {
+			prefix = strings.ToLower(alias)
+		}
+		for _, lang := range getLanguages {
+			if strings.ToLower(lang) == prefix {
+				return lang, true
+			}
+		}
+		index := strings.LastIndex(prefix, "-")
+		if index < 0 {
+			break
+		}
+		prefix = prefix[:index]
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, lang := range getLanguages {
+		if primary, _, _ := strings.Cut(strings.ToLower(lang), "-"); primary == base {
+			return lang, true
+		}
+	}
+	return "", false
+}
@@ -1,14 +1,16 @@
 package i18n
 
 import (
+	"bytes"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"template/global"
+	"template/common/codec"
 
-	"github.com/jingyuexing/go-utils"
 	"github.com/jingyuexing/i18n"
-	"go.uber.org/zap"
 )
 
 
@@ -27,78 +29,109 @@ const (
 	LanguagePersian = "fa"    // 波斯语
 )
 
-var getLanguages = (func() []string {
-	// 获取所有支持的语言文件
-	lang := make([]string, 0)
-	err := filepath.Walk(global.Config.System.Locale, func(path string, info os.FileInfo, err error) error {
+// getLanguages 已加载语言文件的语言, 由 Load 设置
+var getLanguages []string
+
+var initLocal = "en"
+
+// Translate the translate information map
+var Translate = map[string]any{}
+
+// Translate the translate information map for logger
+var TranslateLogger = map[string]any{}
+
+// I18N 调用 Load 之前没有翻译, T 返回原路径
+var I18N *i18n.I18n = newI18n(initLocal)
+
+var LocaleLogger = newLoggerI18n(initLocal)
+
+func newI18n(local string) *i18n.I18n {
+	return i18n.CreateI18n(&i18n.Options{
+		Local:          local,
+		FallbackLocale: initLocal,
+		Message:        Translate,
+	})
+}
+
+func newLoggerI18n(local string) *i18n.I18n {
+	return i18n.CreateI18n(&i18n.Options{
+		Local:          local,
+		FallbackLocale: initLocal,
+		Message:        TranslateLogger,
+	})
+}
+
+// scanLanguages 获取 dir 下所有语言文件, key 为语言代码
+//
+// 文件名格式为 lang.json、lang.yaml、lang.yml 或 lang.toml, 同一语言只能有一个文件
+func scanLanguages(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			global.Logger.Error("Error walking the path", zap.String("path", path))
-			// fmt.Println("Error walking the path:", err)
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
-			// 提取语言代码（假设文件名格式为 lang.json）
-			langCode := strings.TrimSuffix(info.Name(), ".json")
-			lang = append(lang, langCode)
+		if info.IsDir() {
+			return nil
+		}
+		if _, err := codec.FormatOf(path); err != nil {
+			return nil
+		}
+		lang := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+		if exist, ok := files[lang]; ok {
+			return fmt.Errorf("i18n: %s and %s are both locale files of %s", exist, path, lang)
 		}
+		files[lang] = path
 		return nil
 	})
+	return files, err
+}
+
+// Load 加载 dir 下的语言文件, loggerLanguage 为日志使用的语言, 为空时使用英文
+//
+// 应用启动时在处理请求之前调用
+func Load(dir string, loggerLanguage string) error {
+	files, err := scanLanguages(dir)
 	if err != nil {
-		global.Logger.Error("Error enumerating languages", zap.String("error", err.Error()))
+		return err
 	}
-	return lang
-})()
-
-var initLocal = "en"
-
-// Translate the translate information map
-var Translate = (func() map[string]any {
+	languages := slices.Sorted(maps.Keys(files))
 	message := map[string]any{}
-	// 获取所有支持的语言
-	for _, lang := range getLanguages {
-		if _, ok := message[lang]; !ok {
-			// 动态加载语言文件
-			message[lang] = utils.LoadConfig[Locale](
-				utils.Template(global.Config.System.Locale+"/{lang}.json", map[string]any{
-					"lang": lang,
-				}, "{}"))
+	loggerMessage := map[string]any{}
+	loaded := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		data, err := os.ReadFile(files[lang])
+		if err != nil {
+			return err
+		}
+		// 尚未翻译的空文件不作为可用语言
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
 		}
+		locale := new(Locale)
+		format, _ := codec.FormatOf(files[lang])
+		if err := codec.Decode(format, data, locale); err != nil {
+			return fmt.Errorf("i18n: parse %s: %w", lang, err)
+		}
+		message[lang] = locale
+		loggerMessage[lang] = locale.Logger
+		loaded = append(loaded, lang)
 	}
-	return message
-})()
-
-var loggerLanguageInit string = ""
-
-// Translate the translate information map for logger
-var TranslateLogger = (func() map[string]any {
-	// initial language
-	loggerLanguageInit = global.Config.Env.LoggerLanguage
-	if global.Config.Env.LoggerLanguage == "" {
-		loggerLanguageInit = initLocal
+	if loggerLanguage == "" {
+		loggerLanguage = initLocal
 	}
 
-	message := map[string]any{}
-	// 获取所有支持的语言
-	for _, lang := range getLanguages {
-		if _, ok := message[lang]; !ok {
-			// 动态加载语言文件
-			message[lang] = utils.LoadConfig[Locale](
-				utils.Template("locale/{lang}.json", map[string]any{
-					"lang": lang,
-				}, "{}")).Logger
-		}
+	getLanguages, Translate, TranslateLogger = loaded, message, loggerMessage
+	I18N = newI18n(initLocal)
+	LocaleLogger = newLoggerI18n(loggerLanguage)
+	return nil
+}
+
+// T 使用指定语言翻译, locale 为空时使用默认语言; 不修改 I18N 的当前语言, 可以在并发请求中使用
+func T(locale string, path string, args ...map[string]any) string {
+	if locale == "" || locale == I18N.Local {
+		return I18N.T(path, args...)
 	}
-	return message
-})()
-
-var I18N *i18n.I18n = i18n.CreateI18n(&i18n.Options{
-	Local:          initLocal,
-	FallbackLocale: "zh",
-	Message:        Translate,
-})
-
-var LocaleLogger = i18n.CreateI18n(&i18n.Options{
-	Local:          loggerLanguageInit,
-	FallbackLocale: initLocal,
-	Message:        TranslateLogger,
-})
+	translator := *I18N
+	translator.Local = locale
+	return translator.T(path, args...)
+}
@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"template/common/codec"
+	"testing"
+)
+
+// readLocale 读取 locale 目录中的 JSON 语言文件
+func readLocale(t *testing.T, lang string) *Locale {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "locale", lang+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locale := new(Locale)
+	if err := codec.Decode(codec.JSON, data, locale); err != nil {
+		t.Fatalf("decode %s: %v", lang, err)
+	}
+	return locale
+}
+
+// writeLocale 按 name 的扩展名写入语言文件
+func writeLocale(t *testing.T, dir, name string, locale *Locale) {
+	t.Helper()
+	format, err := codec.FormatOf(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := codec.Encode(format, locale)
+	if err != nil {
+		t.Fatalf("encode %s: %v", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func restoreLocales(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Load("../locale", ""); err != nil {
+			t.Errorf("restore locales: %v", err)
+		}
+	})
+}
+
+func TestLoadFormats(t *testing.T) {
+	restoreLocales(t)
+	dir := t.TempDir()
+	en, zh, fr := readLocale(t, LanguageEN), readLocale(t, LanguageZH), readLocale(t, "fr")
+	writeLocale(t, dir, "en.json", en)
+	writeLocale(t, dir, LanguageZH+".yaml", zh)
+	writeLocale(t, dir, "fr.toml", fr)
+	// 尚未翻译的空文件不作为可用语言
+	if err := os.WriteFile(filepath.Join(dir, "de.yml"), []byte("\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# locales"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Load(dir, "fr"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if languages := Languages(); !slices.Equal(languages, []string{"en", "fr", LanguageZH}) {
+		t.Fatalf("Languages() = %v", languages)
+	}
+	cases := map[string]string{
+		LanguageEN: en.Errors.Auth.TokenInvalid,
+		LanguageZH: zh.Errors.Auth.TokenInvalid,
+		"fr":       fr.Errors.Auth.TokenInvalid,
+	}
+	for lang, want := range cases {
+		if got := T(lang, "Errors.Auth.TokenInvalid"); got != want {
+			t.Errorf("T(%s) = %q, want %q", lang, got, want)
+		}
+	}
+	if got, want := T(LanguageZH, "Validate.Required", map[string]any{"field": "name"}), strings.ReplaceAll(zh.Validate.Required, "{field}", "name"); got != want {
+		t.Errorf("T(zh-CN, Validate.Required) = %q, want %q", got, want)
+	}
+	if LocaleLogger.Local != "fr" {
+		t.Errorf("logger language = %q, want fr", LocaleLogger.Local)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	restoreLocales(t)
+	en := readLocale(t, LanguageEN)
+
+	duplicate := t.TempDir()
+	writeLocale(t, duplicate, "en.json", en)
+	writeLocale(t, duplicate, "en.toml", en)
+	if err := Load(duplicate, ""); err == nil || !strings.Contains(err.Error(), "both locale files of en") {
+		t.Fatalf("duplicate language: err = %v", err)
+	}
+
+	invalid := t.TempDir()
+	if err := os.WriteFile(filepath.Join(invalid, "en.yaml"), []byte("errors: [unclosed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(invalid, ""); err == nil || !strings.Contains(err.Error(), "i18n: parse en") {
+		t.Fatalf("invalid file: err = %v", err)
+	}
+}
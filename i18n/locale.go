@@ -10,6 +10,8 @@ type SystemErrors struct {
 	TooManyRequests  string `json:"too_many_requests" yaml:"too_many_requests"`
 	FileNotFound     string `json:"file_not_found" yaml:"file_not_found"`
 	FileTooLarge     string `json:"file_too_large" yaml:"file_too_large"`
+	LimitRate        string `json:"limit_rate" yaml:"limit_rate"`
+	InvalidParams    string `json:"invalid_params" yaml:"invalid_params"`
 }
 
 type AuthErrors struct {
@@ -21,6 +23,13 @@ type AuthErrors struct {
 	UserNameExists    string `json:"username_exists" yaml:"username_exists"`
 	TokenExpired      string `json:"token_expired" yaml:"token_expired"`
 	TokenInvalid      string `json:"token_invalid" yaml:"token_invalid"`
+	TokenRequired     string `json:"token_required" yaml:"token_required"`
+	TokenTypeInvalid  string `json:"token_type_invalid" yaml:"token_type_invalid"`
+	RoleNotFound      string `json:"role_not_found" yaml:"role_not_found"`
+	InvalidPassword   string `json:"invalid_password" yaml:"invalid_password"`
+	TokenRevoked      string `json:"token_revoked" yaml:"token_revoked"`
+	ProviderNotFound  string `json:"provider_not_found" yaml:"provider_not_found"`
+	ExternalLogin     string `json:"external_login" yaml:"external_login"`
 }
 
 // 新增数据库错误定义
@@ -38,10 +47,47 @@ type Errors struct {
 	DB     DBErrors     `json:"db" yaml:"db"`
 }
 
+// Validate 参数校验的错误信息, 可使用 {field} {val} {tag} 占位符
+type Validate struct {
+	LessThanOrEqual   string `json:"less_than_or_equal" yaml:"less_than_or_equal"`
+	GreatThanOrEqual  string `json:"great_than_or_equal" yaml:"great_than_or_equal"`
+	LessThan          string `json:"less_than" yaml:"less_than"`
+	Required          string `json:"required" yaml:"required"`
+	Min               string `json:"min" yaml:"min"`
+	Max               string `json:"max" yaml:"max"`
+	MinLength         string `json:"min_length" yaml:"min_length"`
+	MaxLength         string `json:"max_length" yaml:"max_length"`
+	Enum              string `json:"enum" yaml:"enum"`
+	IsEmail           string `json:"is_email" yaml:"is_email"`
+	ISBN              string `json:"isbn" yaml:"isbn"`
+	HTML              string `json:"html" yaml:"html"`
+	UUID              string `json:"uuid" yaml:"uuid"`
+	MD4               string `json:"md4" yaml:"md4"`
+	MD5               string `json:"md5" yaml:"md5"`
+	CVE               string `json:"cve" yaml:"cve"`
+	Contry            string `json:"country" yaml:"country"`
+	Boolean           string `json:"boolean" yaml:"boolean"`
+	Numberic          string `json:"numberic" yaml:"numberic"`
+	Alphabet          string `json:"alphabet" yaml:"alphabet"`
+	BTC               string `json:"btc" yaml:"btc"`
+	ETH               string `json:"eth" yaml:"eth"`
+	HEX               string `json:"hex" yaml:"hex"`
+	Semver            string `json:"semver" yaml:"semver"`
+	Credit            string `json:"credit" yaml:"credit"`
+	UpperCaseRequired string `json:"upper_case_required" yaml:"upper_case_required"`
+	LowerCaseRequired string `json:"lower_case_required" yaml:"lower_case_required"`
+	NumberRequired    string `json:"number_required" yaml:"number_required"`
+	SpecialChar       string `json:"special_char" yaml:"special_char"`
+	CommonPassword    string `json:"common_password" yaml:"common_password"`
+	Datetime          string `json:"datetime" yaml:"datetime"`
+	Default           string `json:"default" yaml:"default"`
+}
+
 type Logger struct {
 }
 
 type Locale struct {
-	Errors Errors `json:"errors" yaml:"errors"`
-	Logger Logger `json:"logger" yaml:"logger"`
+	Errors   Errors   `json:"errors" yaml:"errors"`
+	Validate Validate `json:"validate" yaml:"validate"`
+	Logger   Logger   `json:"logger" yaml:"logger"`
 }
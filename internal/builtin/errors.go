@@ -33,6 +33,10 @@ const (
 	TokenRequiredCode    // 新增: Token必需错误码
 	InvalidPasswordCode  // 新增: 密码无效错误码
 	TokenTypeInvalidCode // 新增: Token类型无效错误码
+	RoleNotFoundCode
+	TokenRevokedCode
+	ProviderNotFoundCode
+	ExternalLoginFailedCode
 )
 
 // Database operation error codes (3000-3999)
@@ -79,4 +83,8 @@ var (
 	ErrTokenRequired    = builtinerrors.New("Errors.Auth.TokenRequired", http.StatusUnauthorized, TokenRequiredCode)
 	ErrInvalidPassword  = builtinerrors.New("Errors.Auth.InvalidPassword", http.StatusUnauthorized, InvalidPasswordCode)
 	ErrTokenTypeInvalid = builtinerrors.New("Errors.Auth.TokenTypeInvalid", http.StatusUnauthorized, TokenTypeInvalidCode)
+	ErrRoleNotFound     = builtinerrors.New("Errors.Auth.RoleNotFound", http.StatusNotFound, RoleNotFoundCode)
+	ErrTokenRevoked     = builtinerrors.New("Errors.Auth.TokenRevoked", http.StatusUnauthorized, TokenRevokedCode)
+	ErrProviderNotFound = builtinerrors.New("Errors.Auth.ProviderNotFound", http.StatusNotFound, ProviderNotFoundCode)
+	ErrExternalLogin    = builtinerrors.New("Errors.Auth.ExternalLogin", http.StatusUnauthorized, ExternalLoginFailedCode)
 )
@@ -1,34 +1,130 @@
 package builtinerrors
 
-import "template/i18n"
+import (
+	"maps"
+	"slices"
+	"template/i18n"
+)
 
+// Exception 业务错误
+//
+// New 创建的错误作为模板使用, 创建后不会再被修改, 可以在多个请求间共享;
+// WithLocale / WithArgs / WithFieldErrors 返回本次请求的副本, 副本可以用 errors.Is 与模板比较
 type Exception struct {
-	message      string
-	path         string
-	errorMessage map[string]string
-	status       int
-	code         int
+	path   string
+	status int
+	code   int
+
+	locale  string
+	args    []map[string]any
+	fields  map[string]string
+	message string
+	// template 副本对应的模板, 模板自身为 nil
+	template *Exception
+	cause    error
 }
 
+// Error 按语言和参数翻译错误信息
 func (e *Exception) Error() string {
-	return e.message
+	if e.message != "" {
+		return e.message
+	}
+	return i18n.T(e.locale, e.path, e.args...)
 }
 
-func (e *Exception) Format(format ...map[string]any) string {
-	e.message = i18n.I18N.T(e.path, format...)
-	return e.message
+// clone 复制错误, 副本的模板始终指向最初的模板
+func (e *Exception) clone() *Exception {
+	copied := *e
+	copied.template = e.Template()
+	return &copied
 }
 
-func (e *Exception) ErrorMessage() map[string]string {
-	return e.errorMessage
+// Template 错误对应的模板
+func (e *Exception) Template() *Exception {
+	if e.template != nil {
+		return e.template
+	}
+	return e
+}
+
+// WithLocale 使用指定语言翻译错误信息, 为空时使用默认语言
+func (e *Exception) WithLocale(locale string) *Exception {
+	copied := e.clone()
+	copied.locale = locale
+	return copied
+}
+
+// WithArgs 追加错误信息的模板参数
+func (e *Exception) WithArgs(args ...map[string]any) *Exception {
+	copied := e.clone()
+	copied.args = append(slices.Clip(e.args), args...)
+	return copied
+}
+
+// WithFieldErrors 合并字段错误信息
+func (e *Exception) WithFieldErrors(fields map[string]string) *Exception {
+	copied := e.clone()
+	copied.fields = make(map[string]string, len(e.fields)+len(fields))
+	maps.Copy(copied.fields, e.fields)
+	maps.Copy(copied.fields, fields)
+	return copied
+}
+
+// WithFieldError 添加单个字段的错误信息
+func (e *Exception) WithFieldError(field string, message string) *Exception {
+	return e.WithFieldErrors(map[string]string{field: message})
+}
+
+// WithMessage 使用固定的错误信息, 不再翻译
+func (e *Exception) WithMessage(message string) *Exception {
+	copied := e.clone()
+	copied.message = message
+	return copied
 }
 
-func (e *Exception) SetErrorMessage(field string, message string) {
-	e.errorMessage[field] = message
+// Wrap 记录导致错误的底层错误, 可以用 errors.Is / errors.As 查找
+func (e *Exception) Wrap(cause error) *Exception {
+	copied := e.clone()
+	copied.cause = cause
+	return copied
+}
+
+// Unwrap 返回模板和底层错误
+func (e *Exception) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	if e.template != nil {
+		errs = append(errs, e.template)
+	}
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	return errs
+}
+
+// Is 来自同一模板的错误视为相同
+func (e *Exception) Is(target error) bool {
+	other, ok := target.(*Exception)
+	return ok && e.Template() == other.Template()
+}
+
+// Locale 翻译错误信息使用的语言
+func (e *Exception) Locale() string {
+	return e.locale
+}
+
+// Args 错误信息的模板参数
+func (e *Exception) Args() []map[string]any {
+	return slices.Clone(e.args)
+}
+
+// ErrorMessage 字段错误信息, 返回的 map 是副本
+func (e *Exception) ErrorMessage() map[string]string {
+	return maps.Clone(e.fields)
 }
 
-func (e *Exception) SetMessage(message string) {
-	e.message = message
+// Path 错误信息在 i18n 中的路径
+func (e *Exception) Path() string {
+	return e.path
 }
 
 func (e *Exception) Code() int {
@@ -40,5 +136,5 @@ func (e *Exception) Status() int {
 }
 
 func New(path string, status int, code int) *Exception {
-	return &Exception{message: i18n.I18N.T(path), errorMessage: make(map[string]string), path: path, status: status, code: code}
+	return &Exception{path: path, status: status, code: code}
 }
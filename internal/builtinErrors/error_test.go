@@ -0,0 +1,94 @@
+package builtinerrors
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"template/i18n"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	if err := i18n.Load("../../locale", ""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+func TestTemplateIsShared(t *testing.T) {
+	template := New("Validate.Required", 400, 1001)
+	locales := []string{i18n.LanguageEN, i18n.LanguageZH, "de", "ja"}
+	want := make(map[string]string, len(locales))
+	for _, locale := range locales {
+		want[locale] = i18n.T(locale, template.Path(), map[string]any{"field": "name"})
+	}
+	if want[i18n.LanguageEN] == want[i18n.LanguageZH] {
+		t.Fatalf("translations are not loaded: %v", want)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locale := locales[i%len(locales)]
+			field := fmt.Sprintf("field%d", i)
+			err := template.WithLocale(locale).
+				WithArgs(map[string]any{"field": "name"}).
+				WithFieldError(field, locale)
+			if got := err.Error(); got != want[locale] {
+				t.Errorf("%s: Error() = %q, want %q", locale, got, want[locale])
+			}
+			if fields := err.ErrorMessage(); len(fields) != 1 || fields[field] != locale {
+				t.Errorf("%s: ErrorMessage() = %v", locale, fields)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if template.Locale() != "" || len(template.Args()) != 0 || len(template.ErrorMessage()) != 0 {
+		t.Fatalf("template was modified: locale=%q args=%v fields=%v", template.Locale(), template.Args(), template.ErrorMessage())
+	}
+}
+
+func TestIs(t *testing.T) {
+	template := New("Errors.Auth.TokenInvalid", 401, 2002)
+	other := New("Errors.Auth.TokenInvalid", 401, 2002)
+	copied := template.WithLocale(i18n.LanguageZH).WithArgs(map[string]any{"a": 1}).WithFieldError("token", "invalid")
+
+	if !errors.Is(copied, template) {
+		t.Fatal("copy is not the template")
+	}
+	if copied.Template() != template {
+		t.Fatal("Template() of a copy of a copy is not the original template")
+	}
+	if errors.Is(copied, other) {
+		t.Fatal("copy matches a different template with the same path")
+	}
+	if !errors.Is(fmt.Errorf("login: %w", copied), template) {
+		t.Fatal("wrapped copy is not the template")
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	template := New("Errors.System.ServerError", 500, 1000)
+	cause := &fs.PathError{Op: "open", Path: "config.yaml", Err: fs.ErrNotExist}
+	err := template.Wrap(cause).WithLocale(i18n.LanguageEN)
+
+	if !errors.Is(err, template) {
+		t.Fatal("wrapped error is not the template")
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("cause is not found by errors.Is")
+	}
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || pathErr != cause {
+		t.Fatal("cause is not found by errors.As")
+	}
+	if unwrapped := template.Unwrap(); len(unwrapped) != 0 {
+		t.Fatalf("template.Unwrap() = %v, want none", unwrapped)
+	}
+}
@@ -1,9 +1,10 @@
 package main
 
 import (
+	"os"
 	boot "template/boot"
 )
 
 func main(){
-	boot.Startup();
+	boot.Execute(os.Args[1:])
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Audit 审计日志, 将私有接口的访问者和结果写入 logger
+func Audit(logger *zap.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		fields := []zap.Field{
+			zap.String("method", ctx.Request.Method),
+			zap.String("path", ctx.Request.URL.Path),
+			zap.String("route", ctx.FullPath()),
+			zap.String("client_ip", ctx.ClientIP()),
+			zap.Int("status", ctx.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if claims, ok := GetClaims(ctx); ok {
+			fields = append(fields,
+				zap.Uint("uid", claims.Uid),
+				zap.String("gid", claims.GID),
+			)
+		}
+		if len(ctx.Errors) > 0 {
+			fields = append(fields, zap.String("errors", ctx.Errors.String()))
+		}
+		logger.Info("audit", fields...)
+	}
+}
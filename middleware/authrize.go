@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"template/core"
+	"template/internal/builtin"
+	"template/service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthrizeConfig 鉴权中间件配置
+type AuthrizeConfig struct {
+	skipPaths []string
+}
+
+// WithSkipPaths 跳过鉴权的路径
+//
+//	"/api/v1/auth/login"   精确匹配
+//	"/api/v1/public/*"     glob匹配(path.Match), 只匹配一级
+//	"/static/**"           前缀匹配, 匹配 /static 及其所有子路径
+func WithSkipPaths(patterns ...string) Option[AuthrizeConfig] {
+	return func(opt *AuthrizeConfig) {
+		opt.skipPaths = append(opt.skipPaths, patterns...)
+	}
+}
+
+func matchPath(pattern, target string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return target == prefix || strings.HasPrefix(target, prefix+"/")
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		matched, err := path.Match(pattern, target)
+		return err == nil && matched
+	}
+	return pattern == target
+}
+
+func (config *AuthrizeConfig) skip(target string) bool {
+	for _, pattern := range config.skipPaths {
+		if matchPath(pattern, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authrize JWT鉴权中间件, 由 tokens 校验令牌, 通过后将 *auth.OauthClaims 写入上下文
+func Authrize(tokens *auth.AuthService, opts ...Option[AuthrizeConfig]) gin.HandlerFunc {
+	config := applyOptions(&AuthrizeConfig{}, opts...)
+
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodOptions || config.skip(ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+
+		token := core.GetTokenFromRequest(ctx)
+		if token == "" {
+			core.ResponseError(ctx, builtin.ErrTokenRequired)
+			ctx.Abort()
+			return
+		}
+
+		// 校验签名、令牌类型以及是否已退出登录
+		claims, err := tokens.Authenticate(token)
+		if err != nil {
+			core.ResponseError(ctx, err)
+			ctx.Abort()
+			return
+		}
+
+		SetClaims(ctx, claims)
+		ctx.Next()
+	}
+}
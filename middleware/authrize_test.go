@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"template/global/config"
+	"template/service/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestAuth(t *testing.T) *auth.AuthService {
+	t.Helper()
+	tokens, err := auth.New(config.TokenConfig{Type: "Bearer"}, "secret", nil, auth.NewMemoryTokenStore())
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+	return tokens
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, target string
+		want            bool
+	}{
+		{"/api/v1/auth/login", "/api/v1/auth/login", true},
+		{"/api/v1/auth/login", "/api/v1/auth/login/", false},
+		{"/api/v1/auth/login", "/api/v1/auth", false},
+		{"/api/v1/public/*", "/api/v1/public/a", true},
+		{"/api/v1/public/*", "/api/v1/public/a/b", false},
+		{"/api/v1/public/*", "/api/v1/public", false},
+		{"/api/v1/user/?", "/api/v1/user/1", true},
+		{"/api/v1/[", "/api/v1/[", false},
+		{"/static/**", "/static", true},
+		{"/static/**", "/static/css/app.css", true},
+		{"/static/**", "/statics", false},
+		{"/static/**", "/api/static", false},
+	}
+	for _, test := range tests {
+		if got := matchPath(test.pattern, test.target); got != test.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", test.pattern, test.target, got, test.want)
+		}
+	}
+}
+
+func TestAuthrize(t *testing.T) {
+	tokens := newTestAuth(t)
+	access, refresh, err := tokens.Token("alice", "gid", 1)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	revoked, _, err := tokens.Token("bob", "gid2", 2)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	claims, _ := tokens.Authenticate(revoked)
+	if err := tokens.Revoke(claims); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	var gotUID uint
+	engine := gin.New()
+	engine.Use(Authrize(tokens, WithSkipPaths("/public/*", "/static/**")))
+	handler := func(ctx *gin.Context) {
+		gotUID, _ = GetUID(ctx)
+		ctx.Status(http.StatusOK)
+	}
+	engine.GET("/private", handler)
+	engine.GET("/public/:name", handler)
+	engine.GET("/static/*path", handler)
+
+	tests := map[string]struct {
+		path   string
+		token  string
+		status int
+		uid    uint
+	}{
+		"access token":                 {"/private", access, http.StatusOK, 1},
+		"missing token":                {"/private", "", http.StatusUnauthorized, 0},
+		"malformed token":              {"/private", "not-a-token", http.StatusUnauthorized, 0},
+		"refresh token":                {"/private", refresh, http.StatusUnauthorized, 0},
+		"revoked token":                {"/private", revoked, http.StatusUnauthorized, 0},
+		"skip glob":                    {"/public/a", "", http.StatusOK, 0},
+		"skip prefix":                  {"/static/css/app.css", "", http.StatusOK, 0},
+		"X-Token header":               {"/private", "x:" + access, http.StatusOK, 1},
+		"skipped path keeps no claims": {"/public/a", access, http.StatusOK, 0},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotUID = 0
+			header := map[string]string{}
+			if token, ok := strings.CutPrefix(test.token, "x:"); ok {
+				header["X-Token"] = token
+			} else if test.token != "" {
+				header["Authorization"] = "Bearer " + test.token
+			}
+			recorder := serve(engine, test.path, header)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if gotUID != test.uid {
+				t.Fatalf("uid = %d, want %d", gotUID, test.uid)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"template/service/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const claimsContextKey = "middleware.claims"
+
+// SetClaims 将解析后的token信息写入上下文
+func SetClaims(ctx *gin.Context, claims *auth.OauthClaims) {
+	ctx.Set(claimsContextKey, claims)
+}
+
+// GetClaims 获取当前请求的token信息, 未经过 Authrize 时返回 false
+func GetClaims(ctx *gin.Context) (*auth.OauthClaims, bool) {
+	value, ok := ctx.Get(claimsContextKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*auth.OauthClaims)
+	return claims, ok && claims != nil
+}
+
+// GetUID 获取当前登录用户的ID
+func GetUID(ctx *gin.Context) (uint, bool) {
+	claims, ok := GetClaims(ctx)
+	if !ok {
+		return 0, false
+	}
+	return claims.Uid, true
+}
+
+// GetGID 获取当前登录用户的全局唯一ID
+func GetGID(ctx *gin.Context) (string, bool) {
+	claims, ok := GetClaims(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.GID, true
+}
+
+// GetAccountID 获取当前登录用户的账号
+func GetAccountID(ctx *gin.Context) (string, bool) {
+	claims, ok := GetClaims(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.AccountId, true
+}
@@ -0,0 +1,298 @@
+package middleware
+
+import (
+	"container/list"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"template/core"
+	"template/internal/builtin"
+	"template/service/auth"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultLimitCapacity    = 10000            // 最多缓存的限流器数量
+	defaultLimitIdleTimeout = 10 * time.Minute // 限流器空闲回收时间
+)
+
+// KeyFunc 从请求中提取限流维度的key
+type KeyFunc func(ctx *gin.Context) string
+
+type routeLimit struct {
+	qps   rate.Limit
+	burst int
+}
+
+// RateLimit 可以在运行中修改的默认限流速率, 用于配置重新加载
+type RateLimit struct {
+	limit atomic.Pointer[routeLimit]
+}
+
+func NewRateLimit(qps float64, burst int) *RateLimit {
+	limit := new(RateLimit)
+	limit.limit.Store(&routeLimit{qps: rate.Limit(qps), burst: burst})
+	return limit
+}
+
+// Set 修改限流速率, 已有的令牌桶在下一次请求时生效; qps 或 burst 不大于 0 时忽略
+func (limit *RateLimit) Set(qps float64, burst int) {
+	if qps <= 0 || burst <= 0 {
+		return
+	}
+	limit.limit.Store(&routeLimit{qps: rate.Limit(qps), burst: burst})
+}
+
+func (limit *RateLimit) load() routeLimit {
+	return *limit.limit.Load()
+}
+
+// RateLimitConfig 限流中间件配置
+type RateLimitConfig struct {
+	qps         rate.Limit
+	burst       int
+	dynamic     *RateLimit
+	keyFunc     KeyFunc
+	routes      map[string]routeLimit
+	capacity    int
+	idleTimeout time.Duration
+}
+
+// WithQPS 每秒补充的令牌数
+func WithQPS(qps float64) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if qps <= 0 {
+			return
+		}
+		opt.qps = rate.Limit(qps)
+	}
+}
+
+// WithBurst 令牌桶容量
+func WithBurst(burst int) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if burst <= 0 {
+			return
+		}
+		opt.burst = burst
+	}
+}
+
+// WithRateLimit 使用 limit 作为默认限流速率, 代替 WithQPS 和 WithBurst
+func WithRateLimit(limit *RateLimit) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if limit == nil {
+			return
+		}
+		opt.dynamic = limit
+	}
+}
+
+// WithKeyFunc 设置限流维度, 默认按客户端IP
+func WithKeyFunc(keyFunc KeyFunc) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if keyFunc == nil {
+			return
+		}
+		opt.keyFunc = keyFunc
+	}
+}
+
+// WithRouteLimit 为指定路由(gin的路由定义, 如 /api/v1/auth/login)单独设置限流
+func WithRouteLimit(path string, qps float64, burst int) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if path == "" || qps <= 0 || burst <= 0 {
+			return
+		}
+		opt.routes[path] = routeLimit{qps: rate.Limit(qps), burst: burst}
+	}
+}
+
+// WithCapacity 最多缓存的限流器数量, 超出后淘汰最久未使用的
+func WithCapacity(capacity int) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if capacity <= 0 {
+			return
+		}
+		opt.capacity = capacity
+	}
+}
+
+// WithIdleTimeout 限流器空闲超过该时间后被回收
+func WithIdleTimeout(timeout time.Duration) Option[RateLimitConfig] {
+	return func(opt *RateLimitConfig) {
+		if timeout <= 0 {
+			return
+		}
+		opt.idleTimeout = timeout
+	}
+}
+
+// KeyByClientIP 按客户端IP限流
+func KeyByClientIP(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// KeyByRoute 按路由限流, 所有客户端共享同一个令牌桶
+func KeyByRoute(ctx *gin.Context) string {
+	path := ctx.FullPath()
+	if path == "" {
+		path = ctx.Request.URL.Path
+	}
+	return "route:" + ctx.Request.Method + " " + path
+}
+
+// KeyByAPIKey 按请求头中的API Key限流, 没有API Key时退回到客户端IP
+func KeyByAPIKey(header string) KeyFunc {
+	return func(ctx *gin.Context) string {
+		if key := ctx.GetHeader(header); key != "" {
+			return "key:" + key
+		}
+		return KeyByClientIP(ctx)
+	}
+}
+
+// KeyByUID 按登录用户限流, 未经过 Authrize 时由 tokens 解析令牌, 未登录或token无效时退回到客户端IP
+func KeyByUID(tokens *auth.AuthService) KeyFunc {
+	return func(ctx *gin.Context) string {
+		if uid, ok := GetUID(ctx); ok {
+			return "uid:" + strconv.FormatUint(uint64(uid), 10)
+		}
+		token := core.GetTokenFromRequest(ctx)
+		if token == "" {
+			return KeyByClientIP(ctx)
+		}
+		claims, err := tokens.ParseToken(token)
+		if err != nil {
+			return KeyByClientIP(ctx)
+		}
+		return "uid:" + strconv.FormatUint(uint64(claims.Uid), 10)
+	}
+}
+
+type limiterEntry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// limiterStore 带LRU淘汰的限流器缓存
+type limiterStore struct {
+	mu          sync.Mutex
+	capacity    int
+	idleTimeout time.Duration
+	items       map[string]*list.Element
+	order       *list.List
+}
+
+func newLimiterStore(capacity int, idleTimeout time.Duration) *limiterStore {
+	return &limiterStore{
+		capacity:    capacity,
+		idleTimeout: idleTimeout,
+		items:       make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+func (s *limiterStore) get(key string, now time.Time, limit routeLimit) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		entry := elem.Value.(*limiterEntry)
+		entry.lastSeen = now
+		s.order.MoveToFront(elem)
+		return entry.limiter
+	}
+
+	s.evict(now)
+	entry := &limiterEntry{
+		key:      key,
+		limiter:  rate.NewLimiter(limit.qps, limit.burst),
+		lastSeen: now,
+	}
+	s.items[key] = s.order.PushFront(entry)
+	return entry.limiter
+}
+
+// evict 回收空闲的限流器, 仍然超出容量时淘汰最久未使用的
+func (s *limiterStore) evict(now time.Time) {
+	for elem := s.order.Back(); elem != nil; elem = s.order.Back() {
+		entry := elem.Value.(*limiterEntry)
+		if now.Sub(entry.lastSeen) < s.idleTimeout && s.order.Len() < s.capacity {
+			return
+		}
+		s.order.Remove(elem)
+		delete(s.items, entry.key)
+	}
+}
+
+// LimitRate 基于令牌桶的限流中间件
+func LimitRate(opts ...Option[RateLimitConfig]) gin.HandlerFunc {
+	config := applyOptions(&RateLimitConfig{
+		qps:         rate.Limit(10),
+		burst:       20,
+		keyFunc:     KeyByClientIP,
+		routes:      make(map[string]routeLimit),
+		capacity:    defaultLimitCapacity,
+		idleTimeout: defaultLimitIdleTimeout,
+	}, opts...)
+	store := newLimiterStore(config.capacity, config.idleTimeout)
+	defaultLimit := routeLimit{qps: config.qps, burst: config.burst}
+
+	return func(ctx *gin.Context) {
+		limit := defaultLimit
+		if config.dynamic != nil {
+			limit = config.dynamic.load()
+		}
+		key := config.keyFunc(ctx)
+		if route, ok := config.routes[ctx.FullPath()]; ok {
+			limit = route
+			key = ctx.FullPath() + "|" + key
+		}
+
+		now := time.Now()
+		limiter := store.get(key, now, limit)
+		if limiter.Limit() != limit.qps || limiter.Burst() != limit.burst {
+			limiter.SetLimitAt(now, limit.qps)
+			limiter.SetBurstAt(now, limit.burst)
+		}
+		reservation := limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		allowed := reservation.OK() && delay == 0
+		if !allowed {
+			reservation.CancelAt(now)
+		}
+
+		tokens := math.Max(0, limiter.TokensAt(now))
+		core.SetHeaders(ctx, map[string]string{
+			"X-RateLimit-Limit":     strconv.Itoa(limit.burst),
+			"X-RateLimit-Remaining": strconv.Itoa(int(math.Floor(tokens))),
+			"X-RateLimit-Reset":     strconv.Itoa(secondsUntil(float64(limit.burst)-tokens, limit.qps)),
+		})
+
+		if !allowed {
+			retryAfter := int(math.Ceil(delay.Seconds()))
+			if !reservation.OK() || retryAfter < 1 {
+				retryAfter = secondsUntil(1, limit.qps)
+			}
+			ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+			core.ResponseError(ctx, builtin.ErrRequestLimitRate)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// secondsUntil 以qps补充tokens个令牌所需的秒数(向上取整)
+func secondsUntil(tokens float64, qps rate.Limit) int {
+	if tokens <= 0 || qps <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / float64(qps)))
+}
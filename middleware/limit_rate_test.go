@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 测试使用极小的 qps, 请求期间不会补充令牌
+const testQPS = 0.001
+
+func TestLimitRate(t *testing.T) {
+	type request struct {
+		path   string
+		header map[string]string
+		status int
+	}
+	tests := map[string]struct {
+		opts     []Option[RateLimitConfig]
+		requests []request
+	}{
+		"burst exhausted": {
+			opts: []Option[RateLimitConfig]{WithQPS(testQPS), WithBurst(2)},
+			requests: []request{
+				{"/a", nil, http.StatusOK},
+				{"/a", nil, http.StatusOK},
+				{"/a", nil, http.StatusTooManyRequests},
+			},
+		},
+		"keys are independent": {
+			opts: []Option[RateLimitConfig]{WithQPS(testQPS), WithBurst(1), WithKeyFunc(KeyByAPIKey("X-API-Key"))},
+			requests: []request{
+				{"/a", map[string]string{"X-API-Key": "one"}, http.StatusOK},
+				{"/a", map[string]string{"X-API-Key": "two"}, http.StatusOK},
+				{"/a", map[string]string{"X-API-Key": "one"}, http.StatusTooManyRequests},
+				{"/a", nil, http.StatusOK},
+				{"/a", nil, http.StatusTooManyRequests},
+			},
+		},
+		"route shares one bucket": {
+			opts: []Option[RateLimitConfig]{WithQPS(testQPS), WithBurst(1), WithKeyFunc(KeyByRoute)},
+			requests: []request{
+				{"/a", map[string]string{"X-Forwarded-For": "198.51.100.1"}, http.StatusOK},
+				{"/a", map[string]string{"X-Forwarded-For": "198.51.100.2"}, http.StatusTooManyRequests},
+				{"/b", nil, http.StatusOK},
+			},
+		},
+		"route limit": {
+			opts: []Option[RateLimitConfig]{WithQPS(testQPS), WithBurst(2), WithRouteLimit("/login", testQPS, 1)},
+			requests: []request{
+				{"/login", nil, http.StatusOK},
+				{"/login", nil, http.StatusTooManyRequests},
+				{"/a", nil, http.StatusOK},
+				{"/a", nil, http.StatusOK},
+				{"/a", nil, http.StatusTooManyRequests},
+			},
+		},
+		"invalid options are ignored": {
+			opts: []Option[RateLimitConfig]{WithQPS(testQPS), WithBurst(1), WithBurst(0), WithQPS(-1), WithRouteLimit("/a", 0, 0)},
+			requests: []request{
+				{"/a", nil, http.StatusOK},
+				{"/a", nil, http.StatusTooManyRequests},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			engine := newEngine([]gin.HandlerFunc{LimitRate(test.opts...)}, "/a", "/b", "/login")
+			for i, request := range test.requests {
+				if got := serve(engine, request.path, request.header).Code; got != request.status {
+					t.Fatalf("request %d %s: status = %d, want %d", i, request.path, got, request.status)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitRateHeaders(t *testing.T) {
+	engine := newEngine([]gin.HandlerFunc{LimitRate(WithQPS(0.5), WithBurst(2))}, "/a")
+
+	first := serve(engine, "/a", nil)
+	if got := first.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Fatalf("X-RateLimit-Limit = %q, want 2", got)
+	}
+	if got := first.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Fatalf("X-RateLimit-Remaining = %q, want 1", got)
+	}
+	serve(engine, "/a", nil)
+	limited := serve(engine, "/a", nil)
+	if limited.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", limited.Code, http.StatusTooManyRequests)
+	}
+	if got := limited.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After = %q, want 2", got)
+	}
+	if got := limited.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Fatalf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+}
+
+func TestLimitRateDynamic(t *testing.T) {
+	limit := NewRateLimit(testQPS, 1)
+	engine := newEngine([]gin.HandlerFunc{LimitRate(WithRateLimit(limit))}, "/a")
+
+	serve(engine, "/a", nil)
+	if got := serve(engine, "/a", nil).Code; got != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+	limit.Set(testQPS, 3)
+	limit.Set(0, 0)
+	if got := serve(engine, "/a", nil).Header().Get("X-RateLimit-Limit"); got != "3" {
+		t.Fatalf("X-RateLimit-Limit after Set = %q, want 3", got)
+	}
+}
+
+func TestLimiterStoreEvict(t *testing.T) {
+	limit := routeLimit{qps: testQPS, burst: 1}
+	now := time.Now()
+
+	store := newLimiterStore(2, time.Minute)
+	a := store.get("a", now, limit)
+	store.get("b", now, limit)
+	store.get("a", now, limit)
+	store.get("c", now, limit)
+	if _, ok := store.items["b"]; ok {
+		t.Fatal("least recently used limiter was not evicted")
+	}
+	if store.get("a", now, limit) != a {
+		t.Fatal("recently used limiter was evicted")
+	}
+
+	store = newLimiterStore(10, time.Minute)
+	store.get("idle", now, limit)
+	store.get("active", now.Add(2*time.Minute), limit)
+	if _, ok := store.items["idle"]; ok {
+		t.Fatal("idle limiter was not evicted")
+	}
+}
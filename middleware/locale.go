@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"template/i18n"
+	"template/service/user"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleFunc 从请求中读取语言偏好, 没有时返回空字符串
+type LocaleFunc func(ctx *gin.Context) string
+
+// LocaleConfig 语言协商中间件配置
+type LocaleConfig struct {
+	query      string
+	cookie     string
+	preference LocaleFunc
+	fallback   string
+}
+
+// WithLocaleQuery 指定语言的查询参数名, 默认 lang
+func WithLocaleQuery(name string) Option[LocaleConfig] {
+	return func(opt *LocaleConfig) {
+		opt.query = name
+	}
+}
+
+// WithLocaleCookie 保存语言的 cookie 名, 默认 lang
+func WithLocaleCookie(name string) Option[LocaleConfig] {
+	return func(opt *LocaleConfig) {
+		opt.cookie = name
+	}
+}
+
+// WithLocalePreference 用户设置的语言, 优先级低于查询参数和 cookie, 高于 Accept-Language
+func WithLocalePreference(preference LocaleFunc) Option[LocaleConfig] {
+	return func(opt *LocaleConfig) {
+		opt.preference = preference
+	}
+}
+
+// WithDefaultLocale 都没有匹配的语言时使用的语言, 默认为 i18n 的默认语言; locale 未加载时忽略
+func WithDefaultLocale(locale string) Option[LocaleConfig] {
+	return func(opt *LocaleConfig) {
+		if matched, ok := i18n.Match(locale); ok {
+			opt.fallback = matched
+		}
+	}
+}
+
+// acceptLanguages 按权重从高到低排列 Accept-Language 中的语言
+func acceptLanguages(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			tags = append(tags, weighted{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+	languages := make([]string, len(tags))
+	for index, tag := range tags {
+		languages[index] = tag.tag
+	}
+	return languages
+}
+
+// negotiateLocale 依次使用查询参数、cookie、用户设置和 Accept-Language 中第一个已加载的语言
+func (config *LocaleConfig) negotiateLocale(ctx *gin.Context) string {
+	candidates := make([]string, 0, 4)
+	if config.query != "" {
+		candidates = append(candidates, ctx.Query(config.query))
+	}
+	if config.cookie != "" {
+		if value, err := ctx.Cookie(config.cookie); err == nil {
+			candidates = append(candidates, value)
+		}
+	}
+	if config.preference != nil {
+		candidates = append(candidates, config.preference(ctx))
+	}
+	candidates = append(candidates, acceptLanguages(ctx.GetHeader("Accept-Language"))...)
+	for _, candidate := range candidates {
+		if locale, ok := i18n.Match(candidate); ok {
+			return locale
+		}
+	}
+	return config.fallback
+}
+
+// Locale 协商本次请求的语言, 写入请求的 context.Context 供错误信息和参数校验翻译使用
+//
+// 私有分组可以在 Authrize 之后再次使用并加上用户设置:
+//
+//	middleware.Locale(middleware.WithLocalePreference(middleware.UserLocale(users, time.Minute)))
+func Locale(opts ...Option[LocaleConfig]) gin.HandlerFunc {
+	config := applyOptions(&LocaleConfig{query: "lang", cookie: "lang", fallback: i18n.I18N.Local}, opts...)
+
+	return func(ctx *gin.Context) {
+		locale := config.negotiateLocale(ctx)
+		ctx.Request = ctx.Request.WithContext(i18n.WithLocale(ctx.Request.Context(), locale))
+		ctx.Header("Content-Language", locale)
+		ctx.Next()
+	}
+}
+
+// GetLocale 本次请求协商得到的语言, 未经过 Locale 时返回空字符串
+func GetLocale(ctx *gin.Context) string {
+	return i18n.LocaleFromContext(ctx.Request.Context())
+}
+
+// defaultLocaleCapacity 最多缓存的用户语言数量
+const defaultLocaleCapacity = 10000
+
+type localeEntry struct {
+	locale  string
+	expires time.Time
+}
+
+// localeCache 缓存用户设置的语言, 避免每个请求都查询用户
+type localeCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	entries  map[string]localeEntry
+}
+
+func (c *localeCache) get(gid string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[gid]
+	if !ok || now.After(entry.expires) {
+		return "", false
+	}
+	return entry.locale, true
+}
+
+func (c *localeCache) set(gid, locale string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.capacity {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	// 清理过期的记录后仍然已满时全部丢弃, 之后的请求重新查询
+	if len(c.entries) >= c.capacity {
+		clear(c.entries)
+	}
+	c.entries[gid] = localeEntry{locale: locale, expires: now.Add(c.ttl)}
+}
+
+// UserLocale 当前登录用户设置的语言, 需要在 Authrize 之后使用
+//
+// 由 users 查询的语言缓存 ttl 时间, 用户修改语言后最多经过 ttl 生效
+func UserLocale(users *user.UserService, ttl time.Duration) LocaleFunc {
+	cache := &localeCache{ttl: ttl, capacity: defaultLocaleCapacity, entries: make(map[string]localeEntry)}
+	return func(ctx *gin.Context) string {
+		gid, ok := GetGID(ctx)
+		if !ok {
+			return ""
+		}
+		now := time.Now()
+		if locale, ok := cache.get(gid, now); ok {
+			return locale
+		}
+		user, err := users.GetByGID(gid)
+		if err != nil {
+			return ""
+		}
+		cache.set(gid, user.Language, now)
+		return user.Language
+	}
+}
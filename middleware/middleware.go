@@ -0,0 +1,11 @@
+package middleware
+
+// Option 中间件通用配置项
+type Option[T any] func(opt *T)
+
+func applyOptions[T any](config *T, opts ...Option[T]) *T {
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"template/i18n"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := i18n.Load("../locale", ""); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// newEngine 在 middlewares 之后注册 paths 的 GET 路由, 路由返回 200
+func newEngine(middlewares []gin.HandlerFunc, paths ...string) *gin.Engine {
+	engine := gin.New()
+	engine.Use(middlewares...)
+	for _, path := range paths {
+		engine.GET(path, func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+	}
+	return engine
+}
+
+// serve 发送 GET 请求, header 中的值写入请求头
+func serve(engine *gin.Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"template/core"
+	"template/internal/builtin"
+	"template/service/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 要求当前用户拥有全部指定权限, 需放在 Authrize 之后
+//
+// 权限取自令牌, 角色变更在访问令牌刷新后才生效
+//
+//	privateRole.Use(middleware.RequirePermission("role:manage"))
+func RequirePermission(codes ...string) gin.HandlerFunc {
+	required := rbac.MustParsePermissions(codes...)
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			core.ResponseError(ctx, builtin.ErrUnauthorized)
+			ctx.Abort()
+			return
+		}
+		if !claims.Permissions().Has(required) {
+			core.ResponseError(ctx, builtin.ErrForbidden)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireFirstParty 拒绝第三方客户端的令牌, 用于授权同意、退出登录等只能由用户本人操作的接口, 需放在 Authrize 之后
+func RequireFirstParty() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			core.ResponseError(ctx, builtin.ErrUnauthorized)
+			ctx.Abort()
+			return
+		}
+		if claims.ClientID != "" {
+			core.ResponseError(ctx, builtin.ErrForbidden)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireRole 要求当前用户拥有任意一个指定角色, 需放在 Authrize 之后
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			core.ResponseError(ctx, builtin.ErrUnauthorized)
+			ctx.Abort()
+			return
+		}
+		if !rbac.HasAnyRole(claims.Roles(), roles...) {
+			core.ResponseError(ctx, builtin.ErrForbidden)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"template/service/auth"
+	"template/service/rbac"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withClaims 模拟 Authrize, 将 claims 写入上下文
+func withClaims(claims *auth.OauthClaims) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if claims != nil {
+			SetClaims(ctx, claims)
+		}
+		ctx.Next()
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := map[string]struct {
+		claims *auth.OauthClaims
+		codes  []string
+		status int
+	}{
+		"no claims":        {nil, []string{"user:read"}, http.StatusUnauthorized},
+		"no permission":    {&auth.OauthClaims{}, []string{"user:read"}, http.StatusForbidden},
+		"has permission":   {&auth.OauthClaims{Perms: int64(rbac.PermUserRead)}, []string{"user:read"}, http.StatusOK},
+		"missing one":      {&auth.OauthClaims{Perms: int64(rbac.PermUserRead)}, []string{"user:read", "user:write"}, http.StatusForbidden},
+		"has all":          {&auth.OauthClaims{Perms: int64(rbac.PermUserRead | rbac.PermUserWrite)}, []string{"user:read", "user:write"}, http.StatusOK},
+		"wildcard":         {&auth.OauthClaims{Perms: int64(rbac.PermAll)}, []string{"role:manage"}, http.StatusOK},
+		"other permission": {&auth.OauthClaims{Perms: int64(rbac.PermUserWrite)}, []string{"user:read"}, http.StatusForbidden},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			engine := newEngine([]gin.HandlerFunc{withClaims(test.claims), RequirePermission(test.codes...)}, "/a")
+			if got := serve(engine, "/a", nil).Code; got != test.status {
+				t.Fatalf("status = %d, want %d", got, test.status)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionUnknownCode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RequirePermission with an unknown code did not panic")
+		}
+	}()
+	RequirePermission("user:unknown")
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := map[string]struct {
+		claims *auth.OauthClaims
+		status int
+	}{
+		"no claims":  {nil, http.StatusUnauthorized},
+		"no role":    {&auth.OauthClaims{}, http.StatusForbidden},
+		"other role": {&auth.OauthClaims{Permission: "viewer"}, http.StatusForbidden},
+		"any role":   {&auth.OauthClaims{Permission: "viewer,editor"}, http.StatusOK},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			engine := newEngine([]gin.HandlerFunc{withClaims(test.claims), RequireRole("admin", "editor")}, "/a")
+			if got := serve(engine, "/a", nil).Code; got != test.status {
+				t.Fatalf("status = %d, want %d", got, test.status)
+			}
+		})
+	}
+}
+func TestRequireFirstParty(t *testing.T) {
+	tests := map[string]struct {
+		claims *auth.OauthClaims
+		status int
+	}{
+		"no claims":    {nil, http.StatusUnauthorized},
+		"user token":   {&auth.OauthClaims{GID: "gid"}, http.StatusOK},
+		"client token": {&auth.OauthClaims{GID: "gid", ClientID: "client", Scope: "user:read"}, http.StatusForbidden},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			engine := newEngine([]gin.HandlerFunc{withClaims(test.claims), RequireFirstParty()}, "/a")
+			if got := serve(engine, "/a", nil).Code; got != test.status {
+				t.Fatalf("status = %d, want %d", got, test.status)
+			}
+		})
+	}
+}
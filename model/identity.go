@@ -0,0 +1,16 @@
+package model
+
+import "gorm.io/gorm"
+
+// AccountIdentityModel 外部登录提供方的用户与本地账号的关联
+type AccountIdentityModel struct {
+	gorm.Model
+	Provider   string `json:"provider" gorm:"column:provider;uniqueIndex:idx_identity_subject;size:64;comment:'提供方名称'"`
+	Subject    string `json:"subject" gorm:"column:subject;uniqueIndex:idx_identity_subject;size:255;comment:'提供方用户ID'"`
+	AccountGID string `json:"account_gid" gorm:"column:account_gid;index;comment:'账号gid'"`
+	Email      string `json:"email" gorm:"column:email;comment:'提供方返回的邮箱'"`
+}
+
+func (AccountIdentityModel) TableName() string {
+	return "account_identity"
+}
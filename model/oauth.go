@@ -0,0 +1,72 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OauthClientModel OAuth2客户端, 多个值的字段以空格分隔
+type OauthClientModel struct {
+	gorm.Model
+	ClientID     string `json:"client_id" gorm:"column:client_id;uniqueIndex;size:64;comment:'客户端ID'"`
+	SecretHash   string `json:"-" gorm:"column:secret_hash;comment:'客户端密钥哈希, 公开客户端为空'"`
+	Name         string `json:"name" gorm:"column:name;comment:'客户端名称'"`
+	RedirectURIs string `json:"redirect_uris" gorm:"column:redirect_uris;type:text;comment:'回调地址'"`
+	GrantTypes   string `json:"grant_types" gorm:"column:grant_types;comment:'允许的授权类型'"`
+	Scopes       string `json:"scopes" gorm:"column:scopes;type:text;comment:'允许的授权范围'"`
+	Public       bool   `json:"public" gorm:"column:public;comment:'公开客户端, 必须使用PKCE'"`
+	OwnerGID     string `json:"owner_gid" gorm:"column:owner_gid;index;comment:'创建者账号gid'"`
+}
+
+func (OauthClientModel) TableName() string {
+	return "oauth_client"
+}
+
+// AllowRedirectURI 回调地址是否已登记, 要求完全一致
+func (client *OauthClientModel) AllowRedirectURI(uri string) bool {
+	return containsField(client.RedirectURIs, uri)
+}
+
+// AllowGrantType 客户端是否允许使用该授权类型
+func (client *OauthClientModel) AllowGrantType(grantType string) bool {
+	return containsField(client.GrantTypes, grantType)
+}
+
+// AllowScope scope 中的每一项是否都在客户端允许的范围内
+func (client *OauthClientModel) AllowScope(scope string) bool {
+	for _, item := range strings.Fields(scope) {
+		if !containsField(client.Scopes, item) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsField(fields, value string) bool {
+	for _, field := range strings.Fields(fields) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
+
+// OauthCodeModel 授权码, 只保存授权码的哈希, 使用一次后失效
+type OauthCodeModel struct {
+	gorm.Model
+	CodeHash            string     `json:"-" gorm:"column:code_hash;uniqueIndex;size:64;comment:'授权码SHA-256'"`
+	ClientID            string     `json:"client_id" gorm:"column:client_id;index;size:64;comment:'客户端ID'"`
+	AccountGID          string     `json:"account_gid" gorm:"column:account_gid;comment:'授权的账号gid'"`
+	RedirectURI         string     `json:"redirect_uri" gorm:"column:redirect_uri;type:text;comment:'回调地址'"`
+	Scope               string     `json:"scope" gorm:"column:scope;comment:'授权范围'"`
+	CodeChallenge       string     `json:"-" gorm:"column:code_challenge;comment:'PKCE code_challenge'"`
+	CodeChallengeMethod string     `json:"-" gorm:"column:code_challenge_method;size:16;comment:'PKCE 方法'"`
+	ExpiresAt           time.Time  `json:"expires_at" gorm:"column:expires_at;comment:'过期时间'"`
+	UsedAt              *time.Time `json:"used_at" gorm:"column:used_at;comment:'使用时间'"`
+}
+
+func (OauthCodeModel) TableName() string {
+	return "oauth_code"
+}
@@ -0,0 +1,57 @@
+package model
+
+import "gorm.io/gorm"
+
+// RoleModel 角色, Permission 为权限位掩码
+type RoleModel struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"column:name;uniqueIndex;size:64;comment:'角色名'"`
+	Description string `json:"description" gorm:"column:description;comment:'描述'"`
+	Permission  int64  `json:"permission" gorm:"column:permission;comment:'权限位掩码'"`
+}
+
+func (RoleModel) TableName() string {
+	return "role"
+}
+
+// PermissionModel 权限定义, Code 与 Bit 一一对应
+type PermissionModel struct {
+	gorm.Model
+	Code        string `json:"code" gorm:"column:code;uniqueIndex;size:64;comment:'权限标识'"`
+	Bit         int64  `json:"bit" gorm:"column:bit;comment:'权限位'"`
+	Description string `json:"description" gorm:"column:description;comment:'描述'"`
+}
+
+func (PermissionModel) TableName() string {
+	return "permission"
+}
+
+// AccountRoleModel 账号与角色的关联
+type AccountRoleModel struct {
+	gorm.Model
+	AccountGID string `json:"account_gid" gorm:"column:account_gid;index;comment:'账号gid'"`
+	RoleName   string `json:"role_name" gorm:"column:role_name;index;comment:'角色名'"`
+}
+
+func (AccountRoleModel) TableName() string {
+	return "account_role"
+}
+
+// Role选项方法
+func WithRoleName(name string) ModelOption[RoleModel] {
+	return func(r *RoleModel) {
+		r.Name = name
+	}
+}
+
+func WithRoleDescription(description string) ModelOption[RoleModel] {
+	return func(r *RoleModel) {
+		r.Description = description
+	}
+}
+
+func WithRolePermission(permission int64) ModelOption[RoleModel] {
+	return func(r *RoleModel) {
+		r.Permission = permission
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenModel 已签发的刷新令牌, 同一次登录轮换出的令牌属于同一个 family
+type RefreshTokenModel struct {
+	gorm.Model
+	JTI        string     `json:"jti" gorm:"column:jti;uniqueIndex;size:64;comment:'令牌ID'"`
+	FamilyID   string     `json:"family_id" gorm:"column:family_id;index;size:64;comment:'令牌族ID'"`
+	AccountGID string     `json:"account_gid" gorm:"column:account_gid;index;comment:'账号gid'"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at;comment:'过期时间'"`
+	UsedAt     *time.Time `json:"used_at" gorm:"column:used_at;comment:'轮换时间'"`
+	ReplacedBy string     `json:"replaced_by" gorm:"column:replaced_by;size:64;comment:'轮换后的令牌ID'"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at;comment:'吊销时间'"`
+}
+
+func (RefreshTokenModel) TableName() string {
+	return "refresh_token"
+}
+
+// TokenDenylistModel 被吊销的令牌ID、令牌族ID或客户端, 过期后可清理
+type TokenDenylistModel struct {
+	gorm.Model
+	TokenID   string    `json:"token_id" gorm:"column:token_id;uniqueIndex;size:64;comment:'令牌ID、令牌族ID或 client:客户端ID'"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;index;comment:'过期时间'"`
+}
+
+func (TokenDenylistModel) TableName() string {
+	return "token_denylist"
+}
@@ -14,6 +14,8 @@ type UserModel struct {
 	Avatar      string `json:"avatar" gorm:"column:avatar;comment:'头像'"`
 	Follows     int64  `json:"follows" gorm:"column:follows;comment:'粉丝数'"`
 	Following   int64  `json:"following" gorm:"column:following;comment:'关注数'"`
+	Language    string `json:"language" gorm:"column:language;size:16;comment:'偏好语言'"`
+	Version     int64  `json:"version" gorm:"column:version;not null;default:0;comment:'乐观锁版本号'"`
 }
 
 // User选项方法
@@ -1,16 +1,22 @@
 package router
 
-import "github.com/gin-gonic/gin"
+import (
+	api "template/api/v1"
+	"template/middleware"
+)
 
-func authRouterInit(public *gin.RouterGroup, private *gin.RouterGroup) {
+func authRouterInit(controllers *api.API, public *RouterGroup, private *RouterGroup) {
 	publicRouter := public.Group("v1")
-	privateRouter := private.Group("v1")
 	publicAuth := publicRouter.Group("auth")
-	privateAuth := privateRouter.Group("auth")
 
-	authController := API.Auth
-	
-	privateAuth.GET("refresh_token", authController.Refresh)
+	authController := controllers.Auth
+
+	// 刷新令牌由控制器自行校验, 不经过私有分组的鉴权
+	publicAuth.GET("refresh_token", authController.Refresh)
 
 	publicAuth.POST("login", authController.Login)
-}
\ No newline at end of file
+
+	privateAuth := private.Group("v1").Group("auth")
+	// 第三方客户端通过 /oauth2/revoke 吊销令牌
+	privateAuth.POST("logout", middleware.RequireFirstParty(), authController.Logout)
+}
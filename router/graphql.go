@@ -2,15 +2,14 @@ package router
 
 import (
 	"template/api/gql"
-
-	"github.com/gin-gonic/gin"
 )
 
-func graphQLRouterInit(public *gin.RouterGroup, private *gin.RouterGroup) {
+// graphQLRouterInit 注册公开的 GraphQL 路由, 字段的权限由 guard 检查
+func graphQLRouterInit(graphQL gql.GraphQL, public *RouterGroup) {
 	publicAPI := public.Group("v1")
 
 	graphql := publicAPI.Group("graphql")
-	graphql.POST("",gql.GraphQLAPI)
-	graphql.GET("",gql.GraphQLAPI)
+	graphql.POST("", graphQL.GraphQLAPI)
+	graphql.GET("", graphQL.GraphQLAPI)
 
 }
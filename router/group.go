@@ -0,0 +1,159 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteRecord 路由表中的一条记录
+type RouteRecord struct {
+	Group       string
+	Method      string
+	Path        string
+	Handler     string
+	Middlewares []string
+}
+
+type routeTable struct {
+	mu      sync.Mutex
+	records []RouteRecord
+}
+
+func (t *routeTable) add(record RouteRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = append(t.records, record)
+}
+
+// Records 按路径排序后的路由表
+func (t *routeTable) Records() []RouteRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := append([]RouteRecord(nil), t.records...)
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Path == records[j].Path {
+			return records[i].Method < records[j].Method
+		}
+		return records[i].Path < records[j].Path
+	})
+	return records
+}
+
+// Print 打印路由表, 用于确认私有路由没有暴露在公开分组中
+func (t *routeTable) Print() {
+	fmt.Println("route table:")
+	for _, record := range t.Records() {
+		fmt.Printf("  [%-7s] %-7s %-40s --> %s\n", record.Group, record.Method, record.Path, record.Handler)
+		fmt.Printf("  %-9s %-7s %-40s     middleware: %s\n", "", "", "", strings.Join(record.Middlewares, ", "))
+	}
+}
+
+// RouteTable 启动时注册的所有路由
+var RouteTable = new(routeTable)
+
+// RouterGroup 带名称的路由分组, 注册路由时记录所属分组和中间件
+type RouterGroup struct {
+	*gin.RouterGroup
+	name  string
+	table *routeTable
+}
+
+func newRouterGroup(name string, group *gin.RouterGroup, table *routeTable) *RouterGroup {
+	return &RouterGroup{RouterGroup: group, name: name, table: table}
+}
+
+// Name 分组名称
+func (group *RouterGroup) Name() string {
+	return group.name
+}
+
+// Group 创建子分组, 子分组继承名称
+func (group *RouterGroup) Group(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return newRouterGroup(group.name, group.RouterGroup.Group(relativePath, handlers...), group.table)
+}
+
+// Use 为分组追加中间件
+func (group *RouterGroup) Use(middleware ...gin.HandlerFunc) *RouterGroup {
+	group.RouterGroup.Use(middleware...)
+	return group
+}
+
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	group.RouterGroup.Handle(httpMethod, relativePath, handlers...)
+	group.record(httpMethod, relativePath, handlers)
+	return group
+}
+
+func (group *RouterGroup) GET(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+func (group *RouterGroup) POST(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+func (group *RouterGroup) PUT(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+func (group *RouterGroup) PATCH(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+func (group *RouterGroup) DELETE(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodDelete, relativePath, handlers...)
+}
+
+func (group *RouterGroup) OPTIONS(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodOptions, relativePath, handlers...)
+}
+
+func (group *RouterGroup) HEAD(relativePath string, handlers ...gin.HandlerFunc) *RouterGroup {
+	return group.Handle(http.MethodHead, relativePath, handlers...)
+}
+
+func (group *RouterGroup) record(httpMethod, relativePath string, handlers []gin.HandlerFunc) {
+	if group.table == nil || len(handlers) == 0 {
+		return
+	}
+	chain := append(append(gin.HandlersChain{}, group.Handlers...), handlers[:len(handlers)-1]...)
+	middlewares := make([]string, 0, len(chain))
+	for _, handler := range chain {
+		middlewares = append(middlewares, handlerName(handler))
+	}
+	group.table.add(RouteRecord{
+		Group:       group.name,
+		Method:      httpMethod,
+		Path:        joinPaths(group.BasePath(), relativePath),
+		Handler:     handlerName(handlers[len(handlers)-1]),
+		Middlewares: middlewares,
+	})
+}
+
+func joinPaths(base, relativePath string) string {
+	if relativePath == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(relativePath, "/")
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// handlerName 将 template/middleware.Authrize.func1 简化为 middleware.Authrize
+func handlerName(handler gin.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = closureSuffix.ReplaceAllString(name, "")
+	name = strings.TrimSuffix(name, "-fm")
+	if index := strings.LastIndex(name, "/"); index >= 0 {
+		name = name[index+1:]
+	}
+	return name
+}
@@ -0,0 +1,32 @@
+package router
+
+import (
+	api "template/api/v1"
+	"template/middleware"
+)
+
+func oauth2RouterInit(controllers *api.API, public *RouterGroup, private *RouterGroup) {
+	authController := controllers.Auth
+
+	// 令牌、吊销和内省端点使用客户端凭证认证
+	publicOauth := public.Group("v1").Group("oauth2")
+	publicOauth.POST("token", authController.Oauth2Token)
+	publicOauth.POST("refresh", authController.Oauth2Refresh)
+	publicOauth.POST("revoke", authController.Oauth2Logout)
+	publicOauth.POST("introspect", authController.Oauth2Introspect)
+
+	// 外部提供方登录
+	publicOauth.GET("providers", authController.Oauth2Providers)
+	publicOauth.GET("login/:provider", authController.Oauth2Login)
+	publicOauth.GET("callback/:provider", authController.Oauth2Callback)
+
+	// 授权端点需要用户本人已登录, 不接受第三方客户端的令牌
+	privateOauth := private.Group("v1").Group("oauth2")
+	privateOauth.GET("authorize", middleware.RequireFirstParty(), authController.AuthorizeClient)
+	privateOauth.POST("authorize", middleware.RequireFirstParty(), authController.Oauth2Consent)
+
+	privateClient := privateOauth.Group("client", middleware.RequirePermission("client:manage"))
+	privateClient.GET("list", authController.ListClients)
+	privateClient.POST("create", authController.CreateClient)
+	privateClient.DELETE("delete", authController.DeleteClient)
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	api "template/api/v1"
+	"template/middleware"
+)
+
+func roleRouterInit(controllers *api.API, public *RouterGroup, private *RouterGroup) {
+	privateRouter := private.Group("v1")
+	privateRole := privateRouter.Group("role", middleware.RequirePermission("role:manage"))
+
+	roleController := controllers.Role
+
+	privateRole.GET("list", roleController.List)
+	privateRole.GET("account", roleController.Account)
+	privateRole.POST("create", roleController.Create)
+	privateRole.PUT("update", roleController.Update)
+	privateRole.DELETE("delete", roleController.Delete)
+	privateRole.POST("grant", roleController.Grant)
+	privateRole.POST("revoke", roleController.Revoke)
+}
@@ -1,7 +1,8 @@
 package router
 
-import "github.com/gin-gonic/gin"
+import api "template/api/v1"
 
-type RouterBoot = func(public *gin.RouterGroup, private *gin.RouterGroup)
+// RouterBoot 注册模块路由, controllers 为应用创建的控制器
+type RouterBoot = func(controllers *api.API, public *RouterGroup, private *RouterGroup)
 
 type RouterBootList = []RouterBoot
@@ -2,47 +2,78 @@ package router
 
 import (
 	"fmt"
+	"template/api/gql"
 	api "template/api/v1"
-	"template/global"
+	"template/core"
+	"template/global/config"
 	"template/middleware"
+	"template/service"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
-var API = api.AppAPI
-func Routers() *gin.Engine {
-	gin.SetMode(global.Config.Env.GinMode)
+
+// userLocaleTTL 用户设置的语言的缓存时间
+const userLocaleTTL = time.Minute
+
+// privateMiddlewares 私有分组的中间件链, 按顺序执行
+func privateMiddlewares(services *service.Service, conf *config.Configure, logger *zap.Logger) []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		middleware.Authrize(services.Auth),
+		// 登录后用户设置的语言优先于 Accept-Language
+		middleware.Locale(
+			middleware.WithDefaultLocale(conf.System.Language),
+			middleware.WithLocalePreference(middleware.UserLocale(services.User, userLocaleTTL)),
+		),
+		middleware.Audit(logger),
+	}
+}
+
+// Routers 使用 controllers 注册所有路由, 中间件和 GraphQL 使用 services
+//
+// conf.Env.GinMode 为空时使用 gin 的默认模式; rateLimit 为根路由的限流速率, 为空时每秒 1 个令牌, 容量 32
+func Routers(controllers *api.API, services *service.Service, conf *config.Configure, logger *zap.Logger, rateLimit *middleware.RateLimit) (*gin.Engine, error) {
+	if conf.Env.GinMode != "" {
+		gin.SetMode(conf.Env.GinMode)
+	}
+	graphQL, err := gql.New(services)
+	if err != nil {
+		return nil, err
+	}
 
 	fmt.Println("current Mode is: (" + gin.Mode() + ")")
 
 	RootRouter := gin.Default()
 
 	RootRouter.Use(
+		core.ProblemDetails(conf.System.Problem),
+		middleware.Locale(middleware.WithDefaultLocale(conf.System.Language)),
 		middleware.LimitRate(
 			middleware.WithQPS(1),
 			middleware.WithBurst(32),
-		),
-		middleware.Authrize(
-			middleware.WithSkipPaths(
-				"/",
-				"/api",
-			),
+			middleware.WithRateLimit(rateLimit),
 		),
 	)
 
-	publicRouter := RootRouter.Group("/api")
-	//
-	privateRouter := RootRouter.Group("/api")
+	publicRouter := newRouterGroup("public", RootRouter.Group("/api"), RouteTable)
+	// 私有分组与公开分组共用 /api 前缀, 但拥有独立的鉴权和审计中间件
+	privateRouter := newRouterGroup("private", RootRouter.Group("/api", privateMiddlewares(services, conf, logger)...), RouteTable)
 
 	var routerInit RouterBootList = RouterBootList{
 		// your other module router in here
 
 		authRouterInit,
+		oauth2RouterInit,
 		userRouterInit,
-		graphQLRouterInit,
+		roleRouterInit,
 	}
 
 	for _, RB := range routerInit {
-		RB(publicRouter, privateRouter)
+		RB(controllers, publicRouter, privateRouter)
 	}
-	return RootRouter
+	graphQLRouterInit(graphQL, publicRouter)
+	wellKnownRouterInit(controllers, newRouterGroup("public", RootRouter.Group("/.well-known"), RouteTable))
+	RouteTable.Print()
+	return RootRouter, nil
 }
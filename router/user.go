@@ -1,17 +1,20 @@
 package router
 
-import "github.com/gin-gonic/gin"
+import (
+	api "template/api/v1"
+	"template/middleware"
+)
 
-func userRouterInit(public *gin.RouterGroup, private *gin.RouterGroup){
+func userRouterInit(controllers *api.API, public *RouterGroup, private *RouterGroup) {
 	publicRouter := public.Group("v1")
 	privateRouter := private.Group("v1")
 	publicUser := publicRouter.Group("user")
 	privateUser := privateRouter.Group("user")
 
-	userController := API.User
+	userController := controllers.User
 	publicUser.POST("create", userController.CreateUser)
 
-	privateUser.GET("list", userController.List)
+	privateUser.GET("list", middleware.RequirePermission("user:read"), userController.List)
 	privateUser.PUT("update", userController.Update)
 	privateUser.DELETE("delete", userController.Delete)
 }
@@ -0,0 +1,8 @@
+package router
+
+import api "template/api/v1"
+
+// wellKnownRouterInit 注册 RFC 8615 /.well-known 下的公开路由
+func wellKnownRouterInit(controllers *api.API, wellKnown *RouterGroup) {
+	wellKnown.GET("jwks.json", controllers.Auth.JWKS)
+}
@@ -1,8 +1,13 @@
 package account
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"sync"
+	"template/common/password"
 	"template/dao"
+	"template/global/config"
 	"template/dto"
 	"template/internal/builtin"
 	"template/model"
@@ -18,18 +23,63 @@ type IAccountService interface {
 	List(page dto.Pagination) ([]*model.AccountModel, error)
 }
 
-type AccountService struct{}
+// AccountService 通过 New 创建
+type AccountService struct {
+	dao    *dao.Dao
+	hasher *password.Manager
+	// dummyHash 账号不存在时用于校验的哈希, 使登录耗时与账号存在时一致
+	dummyHash func() string
+}
+
+// New 使用 d 访问数据, 按 conf 计算密码哈希
+func New(d *dao.Dao, conf config.PasswordConfig) *AccountService {
+	hasher := password.New(conf)
+	return &AccountService{dao: d, hasher: hasher, dummyHash: sync.OnceValue(func() string {
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		hashed, _ := hasher.Hash(base64.RawURLEncoding.EncodeToString(buf))
+		return hashed
+	})}
+}
 
-var accountDao = dao.APIDao.Account
+// HashPassword 按当前配置计算密码哈希, 空密码表示不修改
+//
+// 输入总是作为明文处理, 即使看起来已经是哈希串, 避免客户端提交预先计算的哈希绕过密码策略
+func (s *AccountService) HashPassword(plain string) (string, error) {
+	if plain == "" {
+		return plain, nil
+	}
+	return s.hasher.Hash(plain)
+}
+
+// Import 导入从其他系统迁移的账号, Password 必须是已知算法的哈希串, 原样保存
+//
+// 只用于迁移等内部流程, 不能传入请求参数
+func (s *AccountService) Import(account *model.AccountModel) error {
+	if !s.hasher.IsHashed(account.Password) {
+		return builtin.ErrInvalidParams
+	}
+	existingAccount, err := s.dao.Account.FindByGID(account.GID)
+	if err == nil && existingAccount != nil {
+		return builtin.ErrUserNameExists
+	}
+	if err = s.dao.Account.Create(account); err != nil {
+		return builtin.ErrDBInsertFailed
+	}
+	return nil
+}
 
 func (s *AccountService) Create(account *model.AccountModel) error {
 	// 检查账号是否已存在
-	existingAccount, err := accountDao.FindByGID(account.GID)
+	existingAccount, err := s.dao.Account.FindByGID(account.GID)
 	if err == nil && existingAccount != nil {
 		return builtin.ErrUserNameExists
 	}
 
-	if err = accountDao.Create(account); err != nil {
+	if account.Password, err = s.HashPassword(account.Password); err != nil {
+		return builtin.ErrInternalServer
+	}
+	if err = s.dao.Account.Create(account); err != nil {
 		return builtin.ErrDBInsertFailed
 	}
 	return nil
@@ -37,12 +87,12 @@ func (s *AccountService) Create(account *model.AccountModel) error {
 
 func (s *AccountService) Delete(gid string) error {
 	// 检查账号是否存在
-	existingAccount, err := accountDao.FindByGID(gid)
+	existingAccount, err := s.dao.Account.FindByGID(gid)
 	if err != nil || existingAccount == nil {
 		return builtin.ErrUserNotFound
 	}
 
-	if err = accountDao.Delete(gid); err != nil {
+	if err = s.dao.Account.Delete(gid); err != nil {
 		return builtin.ErrDBDeleteFailed
 	}
 	return nil
@@ -50,19 +100,22 @@ func (s *AccountService) Delete(gid string) error {
 
 func (s *AccountService) Update(account *model.AccountModel) error {
 	// 检查账号是否存在
-	existingAccount, err := accountDao.FindByGID(account.GID)
+	existingAccount, err := s.dao.Account.FindByGID(account.GID)
 	if err != nil || existingAccount == nil {
 		return builtin.ErrUserNotFound
 	}
 
-	if err = accountDao.Update(account); err != nil {
+	if account.Password, err = s.HashPassword(account.Password); err != nil {
+		return builtin.ErrInternalServer
+	}
+	if err = s.dao.Account.Update(account); err != nil {
 		return builtin.ErrDBUpdateFailed
 	}
 	return nil
 }
 
 func (s *AccountService) GetByGID(gid string) (*model.AccountModel, error) {
-	account, err := accountDao.FindByGID(gid)
+	account, err := s.dao.Account.FindByGID(gid)
 	if err != nil {
 		return nil, builtin.ErrUserNotFound
 	}
@@ -70,7 +123,7 @@ func (s *AccountService) GetByGID(gid string) (*model.AccountModel, error) {
 }
 
 func (s *AccountService) GetByEmail(email string) (*model.AccountModel, error) {
-	account ,err := accountDao.Search(map[string]any{
+	account ,err := s.dao.Account.Search(map[string]any{
 		"email":email,
 	})
 	if err != nil {
@@ -83,9 +136,45 @@ func (s *AccountService) GetByEmail(email string) (*model.AccountModel, error) {
 }
 
 func (s *AccountService) List(pag dto.Pagination) ([]*model.AccountModel, error) {
-	accounts, err := accountDao.List(pag.Page, pag.Size)
+	accounts, err := s.dao.Account.List(pag.Page, pag.Size)
 	if err != nil {
 		return nil, builtin.ErrDBQueryFailed
 	}
 	return accounts, nil
+}
+
+// Authenticate 按邮箱和密码登录
+//
+// 账号不存在时同样校验一次哈希并返回 ErrInvalidPassword, 响应和耗时都不暴露邮箱是否已注册
+func (s *AccountService) Authenticate(email, plain string) (*model.AccountModel, error) {
+	account, err := s.GetByEmail(email)
+	if errors.Is(err, builtin.ErrUserNotFound) {
+		s.hasher.Verify(plain, s.dummyHash())
+		return nil, builtin.ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.VerifyPassword(account, plain); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// VerifyPassword 校验账号密码, 哈希算法或参数过期时透明升级保存的哈希
+func (s *AccountService) VerifyPassword(account *model.AccountModel, plain string) error {
+	hasher := s.hasher
+	ok, rehash, err := hasher.Verify(plain, account.Password)
+	if err != nil || !ok {
+		return builtin.ErrInvalidPassword
+	}
+	if rehash {
+		// 升级失败不影响本次登录, 下次登录时会再次尝试
+		if hashed, err := hasher.Hash(plain); err == nil {
+			if err = s.dao.Account.Update(&model.AccountModel{GID: account.GID, Password: hashed}); err == nil {
+				account.Password = hashed
+			}
+		}
+	}
+	return nil
 }
\ No newline at end of file
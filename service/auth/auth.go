@@ -1,15 +1,22 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"strings"
+	"sync/atomic"
 	parseduration "template/common/parseDuration"
 	"template/common/utils"
-	"template/global"
+	"template/global/config"
 	"template/internal/builtin"
+	"template/model"
+	"template/service/rbac"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 const (
@@ -23,86 +30,471 @@ const (
 	// RefreshTokenExpiry = time.Hour * 24 * 7 // 刷新令牌7天过期
 )
 
+const (
+	defaultAccessTokenDuration  = 25 * time.Minute
+	defaultRefreshTokenDuration = 7 * 24 * time.Hour
+)
+
+// AuthService 通过 New 创建, 签名密钥在创建时按配置加载和校验
 type AuthService struct{
-    tokenSign []byte
-    accessTokenDuration  time.Duration
-    refreshTokenDuration time.Duration
+    keys      *KeyRing
+    // 有效期可以在配置重新加载时修改, 零值使用默认值
+    accessTokenDuration  atomic.Int64
+    refreshTokenDuration atomic.Int64
+    tokenStore           TokenStore
+    tokenType            string
+    rbac                 *rbac.RBACService
 }
 
-var BuiltInAuth = new(AuthService)
-
 type OauthClaims struct {
 	Uid        uint   `json:"uid"`
 	AccountId  string `json:"accountId"`
 	GID        string `json:"gid"`
-	Permission string `json:"role"`
+	Permission string `json:"role"`  // 角色, 多个角色以逗号分隔
+	Perms      int64  `json:"perms"` // 权限位掩码
 	ClientID   string `json:"client_id"`
+	Scope      string `json:"scope,omitempty"` // OAuth2授权范围, 多个以空格分隔
+	FamilyID   string `json:"fid,omitempty"`   // 令牌族ID, 同一次登录轮换出的令牌相同
 	jwt.RegisteredClaims
 }
 
-// New creates a new AuthService instance
-func New() *AuthService {
+// Roles token中携带的角色
+func (claims *OauthClaims) Roles() []string {
+	if claims.Permission == "" {
+		return []string{}
+	}
+	return strings.Split(claims.Permission, ",")
+}
+
+// Permissions token中携带的权限
+func (claims *OauthClaims) Permissions() rbac.Permission {
+	return rbac.Permission(claims.Perms)
+}
+
+// ScopePermissions 第三方客户端令牌的 scope 对应的权限
+func (claims *OauthClaims) ScopePermissions() rbac.Permission {
+	scoped, _ := rbac.ParsePermissions(strings.Fields(claims.Scope)...)
+	return scoped
+}
+
+type claimsContextKey struct{}
+
+// WithClaims 将token信息写入 context.Context, 供GraphQL等非gin的处理函数使用
+func WithClaims(ctx context.Context, claims *OauthClaims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext 从 context.Context 中读取token信息
+func ClaimsFromContext(ctx context.Context) (*OauthClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*OauthClaims)
+	return claims, ok && claims != nil
+}
+
+// New 按 conf 创建 AuthService
+//
+// secret 为未配置签名密钥时 HS256 使用的密钥, grants 查询账号的角色和权限, 为空时令牌不携带角色和权限;
+// store 保存刷新令牌和黑名单, 不能为空
+func New(conf config.TokenConfig, secret string, grants *rbac.RBACService, store TokenStore) (*AuthService, error) {
+    if store == nil {
+        return nil, errors.New("auth: token store is required")
+    }
     auth := &AuthService{
-        tokenSign: []byte(global.Config.System.User.Sign),
-        accessTokenDuration:  25 * time.Minute,
-        refreshTokenDuration: 7 * 24 * time.Hour,
+        tokenStore: store,
+        tokenType:  conf.Type,
+        rbac:       grants,
     }
+    auth.SetTokenDurations(conf)
 
-    // 初始化配置的过期时间
-    if global.Config.System.Token.AccessTokenExpiration != "" {
-        if duration, err := parseduration.ParseDuration(global.Config.System.Token.AccessTokenExpiration); err == nil {
-            auth.accessTokenDuration = duration
-        }
+    // 退役密钥至少在刷新令牌的有效期内保持可校验
+    keys, err := NewKeyRingFromConfig(conf, secret, auth.refreshDuration())
+    if err != nil {
+        return nil, err
     }
+    auth.keys = keys
 
-    if global.Config.System.Token.RefreshTokenExpiration != "" {
-        if duration, err := parseduration.ParseDuration(global.Config.System.Token.RefreshTokenExpiration); err == nil {
-            auth.refreshTokenDuration = duration
-        }
-    }
+    return auth, nil
+}
+
+// SetTokenDurations 修改之后签发的令牌的有效期, 未配置或无法解析时使用默认值
+//
+// 用于配置重新加载, 已签发的令牌和密钥的校验期不受影响
+func (auth *AuthService) SetTokenDurations(conf config.TokenConfig) {
+	auth.accessTokenDuration.Store(int64(parseTokenDuration(conf.AccessTokenExpiration, defaultAccessTokenDuration)))
+	auth.refreshTokenDuration.Store(int64(parseTokenDuration(conf.RefreshTokenExpiration, defaultRefreshTokenDuration)))
+}
+
+func parseTokenDuration(text string, fallback time.Duration) time.Duration {
+	if text == "" {
+		return fallback
+	}
+	duration, err := parseduration.ParseDuration(text)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// accessDuration 访问令牌的有效期, 未设置时使用默认值
+func (auth *AuthService) accessDuration() time.Duration {
+	if duration := time.Duration(auth.accessTokenDuration.Load()); duration > 0 {
+		return duration
+	}
+	return defaultAccessTokenDuration
+}
+
+// refreshDuration 刷新令牌的有效期, 未设置时使用默认值
+func (auth *AuthService) refreshDuration() time.Duration {
+	if duration := time.Duration(auth.refreshTokenDuration.Load()); duration > 0 {
+		return duration
+	}
+	return defaultRefreshTokenDuration
+}
+
+// KeyRing 签名密钥集合, 由 New 按配置创建并校验
+func (auth *AuthService) KeyRing() *KeyRing {
+	return auth.keys
+}
+
+// SetTokenStore 替换令牌存储
+func (auth *AuthService) SetTokenStore(store TokenStore) {
+	auth.tokenStore = store
+}
+
+// newTokenID 生成随机的令牌ID和令牌族ID
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// issueToken 为 subject 签发令牌, subject 中的身份信息和令牌族会写入新令牌
+func (auth *AuthService) issueToken(subject OauthClaims, tokenType string, duration time.Duration) (string, *OauthClaims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", nil, builtin.ErrInternalServer
+	}
+	return auth.signToken(jti, subject, tokenType, duration)
+}
+
+// signToken 使用指定的 jti 签发令牌
+//
+// 第三方客户端的令牌只包含 scope 中声明的权限, 不携带角色
+func (auth *AuthService) signToken(jti string, subject OauthClaims, tokenType string, duration time.Duration) (string, *OauthClaims, error) {
+	var (
+		roles      []string
+		permission rbac.Permission
+		err        error
+	)
+	if subject.GID != "" && auth.rbac != nil {
+		if roles, permission, err = auth.rbac.Grants(subject.GID); err != nil {
+			return "", nil, err
+		}
+	}
+	if subject.ClientID != "" {
+		scoped := subject.ScopePermissions()
+		if subject.GID == "" {
+			permission = scoped
+		} else {
+			permission &= scoped
+		}
+		roles = nil
+	}
+	now := time.Now()
+	claims := &OauthClaims{
+		Uid:        subject.Uid,
+		AccountId:  subject.AccountId,
+		GID:        subject.GID,
+		Permission: strings.Join(roles, ","),
+		Perms:      int64(permission),
+		ClientID:   subject.ClientID,
+		Scope:      subject.Scope,
+		FamilyID:   subject.FamilyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   tokenType,
+		},
+	}
 
-    return auth
+	signed, err := auth.KeyRing().Sign(claims)
+	return signed, claims, err
+}
+
+// issueRefreshToken 使用 jti 签发刷新令牌并保存, 用于轮换和复用检测
+func (auth *AuthService) issueRefreshToken(jti string, subject OauthClaims) (string, *OauthClaims, error) {
+	token, claims, err := auth.signToken(jti, subject, RefreshToken, auth.refreshDuration())
+	if err != nil {
+		return "", nil, err
+	}
+	err = auth.tokenStore.SaveRefresh(&model.RefreshTokenModel{
+		JTI:        claims.ID,
+		FamilyID:   claims.FamilyID,
+		AccountGID: claims.GID,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return "", nil, builtin.ErrDBInsertFailed
+	}
+	return token, claims, nil
+}
+
+// newSubject 创建属于新令牌族的 subject
+func newSubject(username, gid string, id uint) (OauthClaims, error) {
+	family, err := newTokenID()
+	if err != nil {
+		return OauthClaims{}, builtin.ErrInternalServer
+	}
+	return OauthClaims{Uid: id, AccountId: username, GID: gid, FamilyID: family}, nil
 }
 
 // GenerateToken optimization
 func (auth *AuthService) GenerateToken(username, tokenType, gid string, id uint, duration time.Duration) (string, error) {
-    claims := &OauthClaims{
-        Uid:       id,
-        AccountId: username,
-        GID:      gid,
-        RegisteredClaims: jwt.RegisteredClaims{
-            ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-            IssuedAt:  jwt.NewNumericDate(time.Now()),
-            NotBefore: jwt.NewNumericDate(time.Now()),
-            Subject:   tokenType,
-        },
-    }
-
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString(auth.tokenSign)
+	subject, err := newSubject(username, gid, id)
+	if err != nil {
+		return "", err
+	}
+	token, _, err := auth.issueToken(subject, tokenType, duration)
+	return token, err
 }
 
 // CreateAccessToken optimization
 func (auth *AuthService) CreateAccessToken(username, gid string, id uint) (string, error) {
-    return auth.GenerateToken(username, AccessToken, gid, id, auth.accessTokenDuration)
+    return auth.GenerateToken(username, AccessToken, gid, id, auth.accessDuration())
 }
 
-// CreateRefreshToken optimization
+// CreateRefreshToken 签发新令牌族的刷新令牌
 func (auth *AuthService) CreateRefreshToken(username, gid string, id uint) (string, error) {
-    return auth.GenerateToken(username, RefreshToken, gid, id, auth.refreshTokenDuration)
+	subject, err := newSubject(username, gid, id)
+	if err != nil {
+		return "", err
+	}
+	jti, err := newTokenID()
+	if err != nil {
+		return "", builtin.ErrInternalServer
+	}
+	token, _, err := auth.issueRefreshToken(jti, subject)
+	return token, err
 }
 
-// Token optimization
+// Token 登录时签发访问令牌和刷新令牌, 两者属于同一个新的令牌族
 func (auth *AuthService) Token(username, gid string, id uint) (accessToken, refreshToken string, err error) {
-    if accessToken, err = auth.CreateAccessToken(username, gid, id); err != nil {
-        return "", "", err
-    }
+	subject, err := newSubject(username, gid, id)
+	if err != nil {
+		return "", "", err
+	}
+	return auth.tokenPair(subject)
+}
 
-    if refreshToken, err = auth.CreateRefreshToken(username, gid, id); err != nil {
-        return "", "", err
-    }
+// ClientToken 为OAuth2客户端签发令牌, withRefresh 为 false 时只签发访问令牌
+//
+// subject 需要包含 ClientID 和 Scope, 代表用户授权时还需要包含用户信息
+func (auth *AuthService) ClientToken(subject OauthClaims, withRefresh bool) (accessToken, refreshToken string, err error) {
+	family, err := newTokenID()
+	if err != nil {
+		return "", "", builtin.ErrInternalServer
+	}
+	subject.FamilyID = family
+	if !withRefresh {
+		accessToken, _, err = auth.issueToken(subject, AccessToken, auth.accessDuration())
+		return accessToken, "", err
+	}
+	return auth.tokenPair(subject)
+}
+
+// AccessTokenDuration 访问令牌的有效期
+func (auth *AuthService) AccessTokenDuration() time.Duration {
+	return auth.accessDuration()
+}
+
+func (auth *AuthService) tokenPair(subject OauthClaims) (accessToken, refreshToken string, err error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", "", builtin.ErrInternalServer
+	}
+	return auth.tokenPairWithID(jti, subject)
+}
+
+// tokenPairWithID 签发访问令牌和 jti 为 refreshID 的刷新令牌
+func (auth *AuthService) tokenPairWithID(refreshID string, subject OauthClaims) (accessToken, refreshToken string, err error) {
+	if accessToken, _, err = auth.issueToken(subject, AccessToken, auth.accessDuration()); err != nil {
+		return "", "", err
+	}
+
+	if refreshToken, _, err = auth.issueRefreshToken(refreshID, subject); err != nil {
+		return "", "", err
+	}
+
+	return
+}
+
+// Rotate 使用刷新令牌换取新的访问令牌和刷新令牌, 旧的刷新令牌随即失效
+//
+// 已经轮换过的刷新令牌再次被使用时, 视为令牌泄露, 吊销整个令牌族
+func (auth *AuthService) Rotate(refreshToken string) (accessToken, newRefreshToken string, err error) {
+	return auth.rotate(refreshToken, "")
+}
+
+// RotateClient 同 Rotate, 刷新令牌必须属于 clientID
+func (auth *AuthService) RotateClient(refreshToken, clientID string) (accessToken, newRefreshToken string, err error) {
+	return auth.rotate(refreshToken, clientID)
+}
+
+func (auth *AuthService) rotate(refreshToken, clientID string) (accessToken, newRefreshToken string, err error) {
+	claims, err := auth.ParseToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.Subject != RefreshToken {
+		return "", "", builtin.ErrTokenTypeInvalid
+	}
+	if claims.ClientID != clientID {
+		return "", "", builtin.ErrTokenInvalid
+	}
+
+	store := auth.tokenStore
+	record, err := store.FindRefresh(claims.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", "", builtin.ErrTokenInvalid
+		}
+		return "", "", builtin.ErrDBQueryFailed
+	}
+	if revoked, err := auth.IsRevoked(claims); err != nil {
+		return "", "", builtin.ErrDBQueryFailed
+	} else if record.RevokedAt != nil || revoked {
+		return "", "", builtin.ErrTokenRevoked
+	}
+	if record.UsedAt != nil {
+		return "", "", auth.revokeReusedFamily(record.FamilyID)
+	}
+
+	replacedBy, err := newTokenID()
+	if err != nil {
+		return "", "", builtin.ErrInternalServer
+	}
+	// 先标记旧令牌再签发新令牌, 并发使用同一个刷新令牌时只有一个请求能成功标记;
+	// 标记后签发失败时旧令牌同样失效, 需要重新登录
+	marked, err := store.MarkUsed(claims.ID, replacedBy, time.Now())
+	if err != nil {
+		return "", "", builtin.ErrDBUpdateFailed
+	}
+	if !marked {
+		return "", "", auth.revokeReusedFamily(record.FamilyID)
+	}
+
+	subject := *claims
+	subject.FamilyID = record.FamilyID
+	return auth.tokenPairWithID(replacedBy, subject)
+}
 
-    return
+func (auth *AuthService) revokeReusedFamily(family string) error {
+	if err := auth.tokenStore.RevokeFamily(family, time.Now().Add(auth.refreshDuration())); err != nil {
+		return builtin.ErrDBUpdateFailed
+	}
+	return builtin.ErrTokenRevoked
+}
+
+// Revoke 退出登录: 拒绝当前访问令牌, 并吊销其所在令牌族的所有刷新令牌
+func (auth *AuthService) Revoke(claims *OauthClaims) error {
+	store := auth.tokenStore
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := store.Deny(claims.ID, claims.ExpiresAt.Time); err != nil {
+			return builtin.ErrDBInsertFailed
+		}
+	}
+	if claims.FamilyID != "" {
+		if err := store.RevokeFamily(claims.FamilyID, time.Now().Add(auth.refreshDuration())); err != nil {
+			return builtin.ErrDBUpdateFailed
+		}
+	}
+	return nil
+}
+
+// clientDenyID 客户端在黑名单中的ID, 加上前缀避免与令牌ID冲突
+func clientDenyID(clientID string) string {
+	return "client:" + clientID
+}
+
+// RevokeClient 吊销签发给客户端的所有令牌, 用于删除客户端
+func (auth *AuthService) RevokeClient(clientID string) error {
+	if err := auth.tokenStore.Deny(clientDenyID(clientID), time.Now().Add(auth.refreshDuration())); err != nil {
+		return builtin.ErrDBInsertFailed
+	}
+	return nil
+}
+
+// IsRevoked 令牌、其所在的令牌族或所属的客户端是否已被吊销
+func (auth *AuthService) IsRevoked(claims *OauthClaims) (bool, error) {
+	ids := make([]string, 0, 3)
+	if claims.ID != "" {
+		ids = append(ids, claims.ID)
+	}
+	if claims.FamilyID != "" {
+		ids = append(ids, claims.FamilyID)
+	}
+	if claims.ClientID != "" {
+		ids = append(ids, clientDenyID(claims.ClientID))
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+	return auth.tokenStore.IsDenied(ids...)
+}
+
+// Authenticate 校验访问令牌: 签名和有效期, 令牌类型, 以及是否已被吊销
+func (auth *AuthService) Authenticate(tokenString string) (*OauthClaims, error) {
+	claims, err := auth.ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// 刷新令牌等其他类型的令牌不能用于访问接口
+	if claims.Subject != AccessToken {
+		return nil, builtin.ErrTokenTypeInvalid
+	}
+	revoked, err := auth.IsRevoked(claims)
+	if err != nil {
+		return nil, builtin.ErrDBQueryFailed
+	}
+	if revoked {
+		return nil, builtin.ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// Introspect 令牌是否处于有效状态(RFC 7662), 刷新令牌还要求未被轮换或吊销
+func (auth *AuthService) Introspect(tokenString string) (*OauthClaims, bool) {
+	claims, err := auth.ParseToken(tokenString)
+	if err != nil || (claims.Subject != AccessToken && claims.Subject != RefreshToken) {
+		return nil, false
+	}
+	if revoked, err := auth.IsRevoked(claims); err != nil || revoked {
+		return nil, false
+	}
+	if claims.Subject == RefreshToken {
+		record, err := auth.tokenStore.FindRefresh(claims.ID)
+		if err != nil || record.UsedAt != nil || record.RevokedAt != nil {
+			return nil, false
+		}
+	}
+	return claims, true
+}
+
+// RevokeToken 吊销单个令牌(RFC 7009)
+//
+// 刷新令牌会吊销所在的令牌族, 访问令牌加入黑名单; 无效令牌或属于其他客户端的令牌直接忽略
+func (auth *AuthService) RevokeToken(tokenString, clientID string) error {
+	claims, err := auth.ParseToken(tokenString)
+	if err != nil || claims.ClientID != clientID {
+		return nil
+	}
+	if claims.Subject == RefreshToken {
+		return auth.Revoke(&OauthClaims{FamilyID: claims.FamilyID})
+	}
+	return auth.Revoke(&OauthClaims{RegisteredClaims: jwt.RegisteredClaims{ID: claims.ID, ExpiresAt: claims.ExpiresAt}})
 }
 
 // RemovePrefix checks if the input string has the specified prefix.
@@ -116,13 +508,11 @@ func RemovePrefix(input, prefix string) string {
 
 // ParseToken optimization
 func (auth *AuthService) ParseToken(tokenString string) (*OauthClaims, error) {
-    if global.Config.System.Token.Type != "" {
-        tokenString = RemovePrefix(tokenString, global.Config.System.Token.Type+" ")
+    if auth.tokenType != "" {
+        tokenString = RemovePrefix(tokenString, auth.tokenType+" ")
     }
 
-    token, err := jwt.ParseWithClaims(tokenString, &OauthClaims{}, func(token *jwt.Token) (interface{}, error) {
-        return auth.tokenSign, nil
-    })
+    token, err := jwt.ParseWithClaims(tokenString, &OauthClaims{}, auth.KeyRing().Keyfunc)
 
     if err != nil {
         if errors.Is(err, jwt.ErrTokenExpired) {
@@ -136,7 +526,7 @@ func (auth *AuthService) ParseToken(tokenString string) (*OauthClaims, error) {
 }
 
 // ValidateToken 验证token是否有效
-func (auth AuthService) ValidateToken(tokenString string) bool {
+func (auth *AuthService) ValidateToken(tokenString string) bool {
 	_, err := auth.ParseToken(tokenString)
 	return err == nil
 }
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"template/global/config"
+	"template/internal/builtin"
+	"testing"
+	"time"
+)
+
+func newTestAuth(t *testing.T) (*AuthService, *MemoryTokenStore) {
+	t.Helper()
+	store := NewMemoryTokenStore()
+	auth, err := New(config.TokenConfig{}, "secret", nil, store)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return auth, store
+}
+
+func TestRotate(t *testing.T) {
+	auth, store := newTestAuth(t)
+	_, refresh, err := auth.Token("alice", "gid", 1)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	access, next, err := auth.Rotate(refresh)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := auth.Authenticate(access); err != nil {
+		t.Fatalf("Authenticate rotated access token: %v", err)
+	}
+
+	old, _ := auth.ParseToken(refresh)
+	rotated, _ := auth.ParseToken(next)
+	record, err := store.FindRefresh(old.ID)
+	if err != nil {
+		t.Fatalf("FindRefresh: %v", err)
+	}
+	if record.UsedAt == nil || record.ReplacedBy != rotated.ID {
+		t.Fatalf("old refresh token = %+v, want used and replaced by %s", record, rotated.ID)
+	}
+	if rotated.FamilyID != old.FamilyID {
+		t.Fatalf("family = %s, want %s", rotated.FamilyID, old.FamilyID)
+	}
+	if _, ok := auth.Introspect(refresh); ok {
+		t.Fatal("rotated refresh token is still active")
+	}
+	if _, ok := auth.Introspect(next); !ok {
+		t.Fatal("new refresh token is not active")
+	}
+}
+
+func TestRotateReuseRevokesFamily(t *testing.T) {
+	auth, _ := newTestAuth(t)
+	_, refresh, err := auth.Token("alice", "gid", 1)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	access, next, err := auth.Rotate(refresh)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	if _, _, err := auth.Rotate(refresh); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("reuse: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+	if _, _, err := auth.Rotate(next); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("rotate after reuse: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+	if _, err := auth.Authenticate(access); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("access token after reuse: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+}
+
+func TestRotateConcurrent(t *testing.T) {
+	auth, _ := newTestAuth(t)
+	_, refresh, err := auth.Token("alice", "gid", 1)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	const workers = 16
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := auth.Rotate(refresh); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if !errors.Is(err, builtin.ErrTokenRevoked) {
+				t.Errorf("Rotate: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("%d concurrent rotations succeeded, want 1", succeeded)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	auth, _ := newTestAuth(t)
+	access, refresh, err := auth.Token("alice", "gid", 1)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	claims, err := auth.Authenticate(access)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	if err := auth.Revoke(claims); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := auth.Authenticate(access); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("access token after logout: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+	if _, _, err := auth.Rotate(refresh); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("refresh token after logout: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+
+	// 其他令牌族不受影响
+	other, _, err := auth.Token("bob", "gid2", 2)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if _, err := auth.Authenticate(other); err != nil {
+		t.Fatalf("Authenticate other family: %v", err)
+	}
+}
+
+func TestMemoryTokenStoreDenyExpires(t *testing.T) {
+	store := NewMemoryTokenStore()
+	now := time.Now()
+	if err := store.Deny("expired", now.Add(-time.Second)); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+	if err := store.Deny("active", now.Add(time.Minute)); err != nil {
+		t.Fatalf("Deny: %v", err)
+	}
+
+	if denied, _ := store.IsDenied("expired"); denied {
+		t.Fatal("expired entry is still denied")
+	}
+	if denied, _ := store.IsDenied("unknown", "active"); !denied {
+		t.Fatal("active entry is not denied")
+	}
+}
+
+func TestRevokeClient(t *testing.T) {
+	auth, _ := newTestAuth(t)
+	subject := OauthClaims{GID: "gid", ClientID: "client", Scope: "user:read"}
+	access, refresh, err := auth.ClientToken(subject, true)
+	if err != nil {
+		t.Fatalf("ClientToken: %v", err)
+	}
+	other, _, err := auth.ClientToken(OauthClaims{GID: "gid", ClientID: "other", Scope: "user:read"}, false)
+	if err != nil {
+		t.Fatalf("ClientToken: %v", err)
+	}
+
+	if err := auth.RevokeClient("client"); err != nil {
+		t.Fatalf("RevokeClient: %v", err)
+	}
+	if _, err := auth.Authenticate(access); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("access token of revoked client: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+	if _, _, err := auth.RotateClient(refresh, "client"); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("refresh token of revoked client: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+	if _, err := auth.Authenticate(other); err != nil {
+		t.Fatalf("token of other client: %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	parseduration "template/common/parseDuration"
+	"template/global/config"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey 签名密钥
+//
+// 在 [NotBefore, NotAfter) 期间用于签发令牌, NotAfter 之后的 grace 时间内仍可用于校验
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	NotBefore time.Time
+	NotAfter  time.Time // 为零值时一直有效, 直到被轮换
+	private   interface{}
+	public    interface{}
+}
+
+// Symmetric 是否为对称密钥, 对称密钥不会出现在 JWKS 中
+func (key *SigningKey) Symmetric() bool {
+	_, ok := key.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+func (key *SigningKey) canSign(now time.Time) bool {
+	return !now.Before(key.NotBefore) && (key.NotAfter.IsZero() || now.Before(key.NotAfter))
+}
+
+func (key *SigningKey) canVerify(now time.Time, grace time.Duration) bool {
+	return key.NotAfter.IsZero() || now.Before(key.NotAfter.Add(grace))
+}
+
+// NewSigningKey 使用私钥创建签名密钥, HS256 的私钥为 []byte
+func NewSigningKey(id, algorithm string, private interface{}) (*SigningKey, error) {
+	key := &SigningKey{ID: id, private: private}
+	switch algorithm {
+	case AlgorithmHS256:
+		secret, ok := private.([]byte)
+		if !ok || len(secret) == 0 {
+			return nil, fmt.Errorf("auth: %s requires a non-empty secret", algorithm)
+		}
+		key.Method, key.public = jwt.SigningMethodHS256, secret
+	case AlgorithmRS256:
+		rsaKey, ok := private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("auth: %s requires an RSA private key", algorithm)
+		}
+		key.Method, key.public = jwt.SigningMethodRS256, &rsaKey.PublicKey
+	case AlgorithmES256:
+		ecKey, ok := private.(*ecdsa.PrivateKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("auth: %s requires a P-256 private key", algorithm)
+		}
+		key.Method, key.public = jwt.SigningMethodES256, &ecKey.PublicKey
+	case AlgorithmEdDSA:
+		edKey, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("auth: %s requires an Ed25519 private key", algorithm)
+		}
+		key.Method, key.public = jwt.SigningMethodEdDSA, edKey.Public()
+	default:
+		return nil, fmt.Errorf("auth: unsupported signing algorithm %q", algorithm)
+	}
+	return key, nil
+}
+
+// GenerateSigningKey 为非对称算法生成新的随机密钥
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var (
+		private interface{}
+		err     error
+	)
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("auth: cannot generate key for algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	key, err := NewSigningKey(id, algorithm, private)
+	if err != nil {
+		return nil, err
+	}
+	key.NotBefore = time.Now()
+	return key, nil
+}
+
+// LoadSigningKey 从配置的PEM文件中加载签名密钥
+func LoadSigningKey(conf config.SigningKeyConfig, algorithm string) (*SigningKey, error) {
+	if conf.Algorithm != "" {
+		algorithm = conf.Algorithm
+	}
+	data, err := os.ReadFile(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+	var private interface{}
+	switch algorithm {
+	case AlgorithmHS256:
+		private = []byte(strings.TrimSpace(string(data)))
+	case AlgorithmRS256:
+		private, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case AlgorithmES256:
+		private, err = jwt.ParseECPrivateKeyFromPEM(data)
+	case AlgorithmEdDSA:
+		var edKey crypto.PrivateKey
+		edKey, err = jwt.ParseEdPrivateKeyFromPEM(data)
+		private = edKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth: load key %q: %w", conf.ID, err)
+	}
+	key, err := NewSigningKey(conf.ID, algorithm, private)
+	if err != nil {
+		return nil, err
+	}
+	if key.NotBefore, err = parseKeyTime(conf.NotBefore); err != nil {
+		return nil, err
+	}
+	if key.NotAfter, err = parseKeyTime(conf.NotAfter); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func parseKeyTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// NewKeyRingFromConfig 按配置创建密钥集合
+//
+// 未配置密钥时, HS256 使用 secret 作为密钥(不写入 kid); 非对称算法必须配置密钥文件,
+// 否则每次启动和每个实例都会使用不同的密钥, 重启后已签发的令牌全部失效
+func NewKeyRingFromConfig(conf config.TokenConfig, secret string, defaultGrace time.Duration) (*KeyRing, error) {
+	algorithm := conf.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmHS256
+	}
+	var rotation time.Duration
+	if conf.RotationInterval != "" {
+		duration, err := parseduration.ParseDuration(conf.RotationInterval)
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid rotation_interval: %w", err)
+		}
+		rotation = duration
+	}
+	grace := defaultGrace
+	if conf.RotationGrace != "" {
+		duration, err := parseduration.ParseDuration(conf.RotationGrace)
+		if err != nil {
+			return nil, fmt.Errorf("auth: invalid rotation_grace: %w", err)
+		}
+		grace = duration
+	}
+
+	ring := NewKeyRing(algorithm, rotation, grace)
+	for _, keyConf := range conf.Keys {
+		key, err := LoadSigningKey(keyConf, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		ring.Add(key)
+	}
+	if len(conf.Keys) == 0 && algorithm != AlgorithmHS256 {
+		return nil, fmt.Errorf("auth: %s requires signing keys in system.token.keys", algorithm)
+	}
+	if len(conf.Keys) == 0 {
+		key, err := NewSigningKey("", algorithm, []byte(secret))
+		if err != nil {
+			return nil, err
+		}
+		ring.Add(key)
+	}
+	if _, err := ring.Current(); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// KeyRing 签名密钥集合, 支持多个同时有效的密钥和定期轮换
+type KeyRing struct {
+	mu        sync.RWMutex
+	keys      []*SigningKey
+	algorithm string
+	rotation  time.Duration // 为0时不自动轮换
+	grace     time.Duration
+}
+
+// NewKeyRing 创建密钥集合, rotation 大于0时签发前会自动轮换过期的密钥
+func NewKeyRing(algorithm string, rotation, grace time.Duration, keys ...*SigningKey) *KeyRing {
+	return &KeyRing{
+		keys:      keys,
+		algorithm: algorithm,
+		rotation:  rotation,
+		grace:     grace,
+	}
+}
+
+// Add 添加密钥, 可以提前加入尚未启用的密钥, 让校验方先获取到公钥
+func (ring *KeyRing) Add(key *SigningKey) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	ring.keys = append(ring.keys, key)
+}
+
+// current 当前用于签发的密钥, 多个可用时选择最新启用的
+func (ring *KeyRing) current(now time.Time) *SigningKey {
+	var current *SigningKey
+	for _, key := range ring.keys {
+		if key.canSign(now) && (current == nil || key.NotBefore.After(current.NotBefore)) {
+			current = key
+		}
+	}
+	return current
+}
+
+// Current 返回当前签发密钥, 到达轮换周期时生成新密钥并让旧密钥退役
+func (ring *KeyRing) Current() (*SigningKey, error) {
+	now := time.Now()
+	ring.mu.RLock()
+	current := ring.current(now)
+	ring.mu.RUnlock()
+	if current != nil && !ring.shouldRotate(current, now) {
+		return current, nil
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	current = ring.current(now)
+	if current != nil && !ring.shouldRotate(current, now) {
+		return current, nil
+	}
+	if current != nil && current.Symmetric() {
+		return current, nil
+	}
+	// 只轮换已有的密钥, 没有可用的密钥时不自动生成; 对称密钥无法自动生成, 只能来自配置
+	if current == nil || ring.algorithm == AlgorithmHS256 {
+		return nil, errors.New("auth: no active signing key")
+	}
+	return ring.rotate(current, now)
+}
+
+func (ring *KeyRing) shouldRotate(key *SigningKey, now time.Time) bool {
+	return ring.rotation > 0 && key.NotAfter.IsZero() && !now.Before(key.NotBefore.Add(ring.rotation))
+}
+
+// Rotate 立即生成新密钥, 旧密钥停止签发并在 grace 时间内继续用于校验
+func (ring *KeyRing) Rotate() (*SigningKey, error) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	now := time.Now()
+	return ring.rotate(ring.current(now), now)
+}
+
+func (ring *KeyRing) rotate(current *SigningKey, now time.Time) (*SigningKey, error) {
+	key, err := GenerateSigningKey(ring.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key.NotBefore = now
+	if current != nil {
+		current.NotAfter = now
+	}
+	ring.keys = append(ring.keys, key)
+	ring.prune(now)
+	return key, nil
+}
+
+// prune 移除已经超过 grace 时间的退役密钥
+func (ring *KeyRing) prune(now time.Time) {
+	keys := ring.keys[:0]
+	for _, key := range ring.keys {
+		if key.canVerify(now, ring.grace) {
+			keys = append(keys, key)
+		}
+	}
+	ring.keys = keys
+}
+
+// Lookup 按 kid 查找可用于校验的密钥, kid 为空时使用当前签发密钥
+func (ring *KeyRing) Lookup(kid string) (*SigningKey, bool) {
+	now := time.Now()
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	if kid == "" {
+		key := ring.current(now)
+		return key, key != nil
+	}
+	for _, key := range ring.keys {
+		if key.ID == kid && key.canVerify(now, ring.grace) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Sign 使用当前密钥签发令牌, 并在头部写入 kid
+func (ring *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	key, err := ring.Current()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.private)
+}
+
+// Keyfunc 供 jwt.Parse 使用, 按 kid 选择密钥并拒绝与密钥不符的算法
+func (ring *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ring.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.public, nil
+}
+
+// JWK RFC 7517 公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS RFC 7517 公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出所有可用于校验的非对称公钥, 包括尚未启用和处于 grace 期间的密钥
+func (ring *KeyRing) JWKS() JWKS {
+	now := time.Now()
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ring.keys {
+		if key.Symmetric() || !key.canVerify(now, ring.grace) {
+			continue
+		}
+		if jwk, ok := publicJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.SliceStable(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func publicJWK(key *SigningKey) (JWK, bool) {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty, jwk.Crv = "EC", public.Curve.Params().Name
+		jwk.X = encode(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+		jwk.X = encode(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"sync"
+	"template/dao/token"
+	"template/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenStore 刷新令牌和令牌黑名单的存储
+type TokenStore interface {
+	SaveRefresh(token *model.RefreshTokenModel) error
+	FindRefresh(jti string) (*model.RefreshTokenModel, error)
+	// MarkUsed 将未使用的刷新令牌标记为已轮换, 令牌已被使用或吊销时返回 false
+	MarkUsed(jti, replacedBy string, at time.Time) (bool, error)
+	// RevokeFamily 吊销令牌族中的所有刷新令牌, 并在 until 之前拒绝该令牌族签发的访问令牌
+	RevokeFamily(familyID string, until time.Time) error
+	// Deny 在 until 之前拒绝该令牌ID
+	Deny(id string, until time.Time) error
+	// IsDenied 任意一个ID在黑名单中时返回 true
+	IsDenied(ids ...string) (bool, error)
+}
+
+var _ TokenStore = (*token.TokenDao)(nil)
+
+// MemoryTokenStore 基于内存的令牌存储, 用于测试和单实例部署
+type MemoryTokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]*model.RefreshTokenModel
+	deny    map[string]time.Time
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		refresh: make(map[string]*model.RefreshTokenModel),
+		deny:    make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryTokenStore) SaveRefresh(token *model.RefreshTokenModel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := *token
+	s.refresh[token.JTI] = &record
+	return nil
+}
+
+func (s *MemoryTokenStore) FindRefresh(jti string) (*model.RefreshTokenModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.refresh[jti]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	result := *record
+	return &result, nil
+}
+
+func (s *MemoryTokenStore) MarkUsed(jti, replacedBy string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.refresh[jti]
+	if !ok || record.UsedAt != nil || record.RevokedAt != nil {
+		return false, nil
+	}
+	record.UsedAt = &at
+	record.ReplacedBy = replacedBy
+	return true, nil
+}
+
+func (s *MemoryTokenStore) RevokeFamily(familyID string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, record := range s.refresh {
+		if record.FamilyID == familyID && record.RevokedAt == nil {
+			record.RevokedAt = &now
+		}
+	}
+	s.deny[familyID] = until
+	return nil
+}
+
+func (s *MemoryTokenStore) Deny(id string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deny[id] = until
+	return nil
+}
+
+func (s *MemoryTokenStore) IsDenied(ids ...string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, id := range ids {
+		until, ok := s.deny[id]
+		if !ok {
+			continue
+		}
+		if now.Before(until) {
+			return true, nil
+		}
+		delete(s.deny, id)
+	}
+	return false, nil
+}
@@ -0,0 +1,47 @@
+package oauth
+
+import "net/http"
+
+// Error RFC 6749 第5.2节定义的错误响应, 直接序列化为响应体
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	status      int
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// Status HTTP状态码
+func (e *Error) Status() int {
+	return e.status
+}
+
+// WithDescription 返回带有说明的副本, 不修改预定义的错误
+func (e *Error) WithDescription(description string) *Error {
+	clone := *e
+	clone.Description = description
+	return &clone
+}
+
+// Is 按错误码比较, 使带说明的副本也能匹配预定义的错误
+func (e *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other.Code == e.Code
+}
+
+var (
+	ErrInvalidRequest          = &Error{Code: "invalid_request", status: http.StatusBadRequest}
+	ErrInvalidClient           = &Error{Code: "invalid_client", status: http.StatusUnauthorized}
+	ErrInvalidGrant            = &Error{Code: "invalid_grant", status: http.StatusBadRequest}
+	ErrUnauthorizedClient      = &Error{Code: "unauthorized_client", status: http.StatusBadRequest}
+	ErrUnsupportedGrantType    = &Error{Code: "unsupported_grant_type", status: http.StatusBadRequest}
+	ErrUnsupportedResponseType = &Error{Code: "unsupported_response_type", status: http.StatusBadRequest}
+	ErrInvalidScope            = &Error{Code: "invalid_scope", status: http.StatusBadRequest}
+	ErrAccessDenied            = &Error{Code: "access_denied", status: http.StatusForbidden}
+	ErrServerError             = &Error{Code: "server_error", status: http.StatusInternalServerError}
+)
@@ -0,0 +1,483 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"template/common/password"
+	"template/dao/account"
+	"template/dao/oauth"
+	"template/global/config"
+	"template/internal/builtin"
+	"template/model"
+	"template/service/auth"
+	"template/service/rbac"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 授权类型
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// PKCE code_challenge_method
+const (
+	ChallengePlain = "plain"
+	ChallengeS256  = "S256"
+)
+
+// codeLifetime 授权码有效期, RFC 6749 建议不超过10分钟
+const codeLifetime = 10 * time.Minute
+
+type IOauthService interface {
+	RegisterClient(client *model.OauthClientModel, creator *auth.OauthClaims) (string, error)
+	ListClients(ownerGID string) ([]*model.OauthClientModel, error)
+	DeleteClient(clientID, ownerGID string) error
+	AuthenticateClient(clientID, secret string) (*model.OauthClientModel, error)
+	ValidateAuthorize(request *AuthorizeRequest) (*model.OauthClientModel, error)
+	IssueCode(request *AuthorizeRequest, accountGID string) (string, error)
+	ExchangeCode(client *model.OauthClientModel, code, redirectURI, verifier string) (*TokenResponse, error)
+	ClientCredentials(client *model.OauthClientModel, scope string) (*TokenResponse, error)
+	Refresh(client *model.OauthClientModel, refreshToken string) (*TokenResponse, error)
+	Introspect(client *model.OauthClientModel, token string) *IntrospectionResponse
+	Revoke(client *model.OauthClientModel, token string) error
+}
+
+// ClientStore 客户端和授权码的存储
+type ClientStore interface {
+	CreateClient(client *model.OauthClientModel) error
+	FindClient(clientID string) (*model.OauthClientModel, error)
+	ListClients(ownerGID string) ([]*model.OauthClientModel, error)
+	DeleteClient(clientID string) error
+	SaveCode(code *model.OauthCodeModel) error
+	// ConsumeCode 将授权码标记为已使用, 授权码已被使用过时第二个返回值为 false, 不存在时返回 gorm.ErrRecordNotFound
+	ConsumeCode(codeHash string, at time.Time) (*model.OauthCodeModel, bool, error)
+}
+
+// AccountStore 查找授权码对应的账号
+type AccountStore interface {
+	FindByGID(gid string) (*model.AccountModel, error)
+}
+
+var (
+	_ ClientStore  = (*oauth.OauthDao)(nil)
+	_ AccountStore = (*account.AccountDao)(nil)
+)
+
+// OauthService 通过 New 创建
+type OauthService struct {
+	clients  ClientStore
+	accounts AccountStore
+	tokens   *auth.AuthService
+	hasher   *password.Manager
+}
+
+// New 客户端和授权码保存在 clients, 授权码对应的账号从 accounts 查找, 由 tokens 签发令牌, 客户端密钥按 conf 哈希
+func New(clients ClientStore, accounts AccountStore, tokens *auth.AuthService, conf config.PasswordConfig) *OauthService {
+	return &OauthService{clients: clients, accounts: accounts, tokens: tokens, hasher: password.New(conf)}
+}
+
+// AuthorizeRequest 授权请求参数(RFC 6749 第4.1.1节, RFC 7636 第4.3节)
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// TokenResponse 令牌响应(RFC 6749 第5.1节)
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse 令牌内省响应(RFC 7662 第2.2节)
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// RegisterClient 注册客户端, 返回的明文密钥只在注册时出现一次, 公开客户端没有密钥
+//
+// 客户端的 scope 不能包含 *, 也不能超出创建者 creator 自身的权限, 否则客户端可以借 client_credentials 提权
+func (s *OauthService) RegisterClient(client *model.OauthClientModel, creator *auth.OauthClaims) (string, error) {
+	scopes := strings.Fields(client.Scopes)
+	scoped, unknown := rbac.ParsePermissions(scopes...)
+	if len(unknown) > 0 || slices.Contains(scopes, "*") {
+		return "", builtin.ErrInvalidParams
+	}
+	if !creator.Permissions().Has(scoped) {
+		return "", builtin.ErrForbidden
+	}
+	for _, grantType := range strings.Fields(client.GrantTypes) {
+		switch grantType {
+		case GrantAuthorizationCode, GrantRefreshToken:
+		case GrantClientCredentials:
+			// 公开客户端无法保管密钥, 不能代表自身获取令牌
+			if client.Public {
+				return "", builtin.ErrInvalidParams
+			}
+		default:
+			return "", builtin.ErrInvalidParams
+		}
+	}
+
+	clientID, err := randomString(16)
+	if err != nil {
+		return "", builtin.ErrInternalServer
+	}
+	client.ClientID = clientID
+
+	secret := ""
+	if !client.Public {
+		if secret, err = randomString(32); err != nil {
+			return "", builtin.ErrInternalServer
+		}
+		if client.SecretHash, err = s.hasher.Hash(secret); err != nil {
+			return "", builtin.ErrInternalServer
+		}
+	}
+	if err := s.clients.CreateClient(client); err != nil {
+		return "", builtin.ErrDBInsertFailed
+	}
+	return secret, nil
+}
+
+// ListClients ownerGID 为空时返回全部客户端
+func (s *OauthService) ListClients(ownerGID string) ([]*model.OauthClientModel, error) {
+	clients, err := s.clients.ListClients(ownerGID)
+	if err != nil {
+		return nil, builtin.ErrDBQueryFailed
+	}
+	return clients, nil
+}
+
+// DeleteClient 删除客户端并吊销签发给它的所有令牌, ownerGID 不为空时只能删除该用户创建的客户端
+func (s *OauthService) DeleteClient(clientID, ownerGID string) error {
+	client, err := s.findClient(clientID)
+	if err != nil || (ownerGID != "" && client.OwnerGID != ownerGID) {
+		return builtin.ErrNotFound
+	}
+	if err := s.tokens.RevokeClient(clientID); err != nil {
+		return err
+	}
+	if err := s.clients.DeleteClient(clientID); err != nil {
+		return builtin.ErrDBDeleteFailed
+	}
+	return nil
+}
+
+func (s *OauthService) findClient(clientID string) (*model.OauthClientModel, error) {
+	if clientID == "" {
+		return nil, ErrInvalidClient
+	}
+	client, err := s.clients.FindClient(clientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidClient
+		}
+		return nil, ErrServerError
+	}
+	return client, nil
+}
+
+// AuthenticateClient 校验客户端凭证, 公开客户端只需要 client_id
+func (s *OauthService) AuthenticateClient(clientID, secret string) (*model.OauthClientModel, error) {
+	client, err := s.findClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.Public {
+		return client, nil
+	}
+	if secret == "" {
+		return nil, ErrInvalidClient
+	}
+	ok, _, err := s.hasher.Verify(secret, client.SecretHash)
+	if err != nil || !ok {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// ValidateAuthorize 校验授权请求, 补全默认的回调地址、scope 和 PKCE 方法
+//
+// 公开客户端必须使用 S256, 机密客户端省略 code_challenge_method 时为 plain
+//
+// 客户端或回调地址无效时不能重定向到回调地址, 其余错误可以通过回调地址返回给客户端
+func (s *OauthService) ValidateAuthorize(request *AuthorizeRequest) (*model.OauthClientModel, error) {
+	client, err := s.findClient(request.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if request.RedirectURI == "" {
+		uris := strings.Fields(client.RedirectURIs)
+		if len(uris) != 1 {
+			return nil, ErrInvalidRequest.WithDescription("redirect_uri is required")
+		}
+		request.RedirectURI = uris[0]
+	}
+	if !client.AllowRedirectURI(request.RedirectURI) {
+		return nil, ErrInvalidRequest.WithDescription("redirect_uri is not registered")
+	}
+
+	if request.ResponseType != "code" {
+		return client, ErrUnsupportedResponseType
+	}
+	if !client.AllowGrantType(GrantAuthorizationCode) {
+		return client, ErrUnauthorizedClient
+	}
+	if request.Scope == "" {
+		request.Scope = client.Scopes
+	}
+	if !client.AllowScope(request.Scope) {
+		return client, ErrInvalidScope
+	}
+
+	if request.CodeChallenge == "" {
+		if client.Public {
+			return client, ErrInvalidRequest.WithDescription("code_challenge is required")
+		}
+		return client, nil
+	}
+	if client.Public && request.CodeChallengeMethod != ChallengeS256 {
+		return client, ErrInvalidRequest.WithDescription("code_challenge_method must be S256")
+	}
+	if request.CodeChallengeMethod == "" {
+		request.CodeChallengeMethod = ChallengePlain
+	}
+	if request.CodeChallengeMethod != ChallengePlain && request.CodeChallengeMethod != ChallengeS256 {
+		return client, ErrInvalidRequest.WithDescription("unsupported code_challenge_method")
+	}
+	return client, nil
+}
+
+// IssueCode 用户同意授权后签发授权码, request 需要先经过 ValidateAuthorize
+func (s *OauthService) IssueCode(request *AuthorizeRequest, accountGID string) (string, error) {
+	code, err := randomString(32)
+	if err != nil {
+		return "", ErrServerError
+	}
+	err = s.clients.SaveCode(&model.OauthCodeModel{
+		CodeHash:            hashCode(code),
+		ClientID:            request.ClientID,
+		AccountGID:          accountGID,
+		RedirectURI:         request.RedirectURI,
+		Scope:               request.Scope,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(codeLifetime),
+	})
+	if err != nil {
+		return "", ErrServerError
+	}
+	return code, nil
+}
+
+// verifyChallenge 校验 PKCE code_verifier(RFC 7636 第4.6节)
+func verifyChallenge(code *model.OauthCodeModel, verifier string) bool {
+	if code.CodeChallenge == "" {
+		return true
+	}
+	if verifier == "" {
+		return false
+	}
+	computed := verifier
+	if code.CodeChallengeMethod == ChallengeS256 {
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(code.CodeChallenge)) == 1
+}
+
+// ExchangeCode authorization_code 授权, 授权码只能使用一次
+func (s *OauthService) ExchangeCode(client *model.OauthClientModel, code, redirectURI, verifier string) (*TokenResponse, error) {
+	if !client.AllowGrantType(GrantAuthorizationCode) {
+		return nil, ErrUnauthorizedClient
+	}
+	if code == "" {
+		return nil, ErrInvalidRequest.WithDescription("code is required")
+	}
+	record, ok, err := s.clients.ConsumeCode(hashCode(code), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, ErrServerError
+	}
+	if !ok || time.Now().After(record.ExpiresAt) || record.ClientID != client.ClientID {
+		return nil, ErrInvalidGrant
+	}
+	// 授权请求省略 redirect_uri 时, 令牌请求也可以省略
+	if uris := strings.Fields(client.RedirectURIs); redirectURI == "" && len(uris) == 1 {
+		redirectURI = uris[0]
+	}
+	if record.RedirectURI != redirectURI {
+		return nil, ErrInvalidGrant.WithDescription("redirect_uri mismatch")
+	}
+	if !verifyChallenge(record, verifier) {
+		return nil, ErrInvalidGrant.WithDescription("invalid code_verifier")
+	}
+
+	account, err := s.accounts.FindByGID(record.AccountGID)
+	if err != nil {
+		return nil, ErrInvalidGrant
+	}
+	subject := auth.OauthClaims{
+		Uid:       account.ID,
+		AccountId: account.Email,
+		GID:       account.GID,
+		ClientID:  client.ClientID,
+		Scope:     record.Scope,
+	}
+	return s.token(subject, client.AllowGrantType(GrantRefreshToken))
+}
+
+// ClientCredentials client_credentials 授权, 令牌代表客户端自身, 不签发刷新令牌
+func (s *OauthService) ClientCredentials(client *model.OauthClientModel, scope string) (*TokenResponse, error) {
+	if client.Public || !client.AllowGrantType(GrantClientCredentials) {
+		return nil, ErrUnauthorizedClient
+	}
+	if scope == "" {
+		scope = client.Scopes
+	}
+	if !client.AllowScope(scope) {
+		return nil, ErrInvalidScope
+	}
+	subject := auth.OauthClaims{
+		AccountId: client.ClientID,
+		ClientID:  client.ClientID,
+		Scope:     scope,
+	}
+	return s.token(subject, false)
+}
+
+// Refresh refresh_token 授权, 刷新令牌会被轮换
+func (s *OauthService) Refresh(client *model.OauthClientModel, refreshToken string) (*TokenResponse, error) {
+	if !client.AllowGrantType(GrantRefreshToken) {
+		return nil, ErrUnauthorizedClient
+	}
+	if refreshToken == "" {
+		return nil, ErrInvalidRequest.WithDescription("refresh_token is required")
+	}
+	authService := s.tokens
+	accessToken, newRefreshToken, err := authService.RotateClient(refreshToken, client.ClientID)
+	if err != nil {
+		return nil, grantError(err)
+	}
+	claims, err := authService.ParseToken(accessToken)
+	if err != nil {
+		return nil, ErrServerError
+	}
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(authService.AccessTokenDuration().Seconds()),
+		RefreshToken: newRefreshToken,
+		Scope:        claims.Scope,
+	}, nil
+}
+
+func (s *OauthService) token(subject auth.OauthClaims, withRefresh bool) (*TokenResponse, error) {
+	authService := s.tokens
+	accessToken, refreshToken, err := authService.ClientToken(subject, withRefresh)
+	if err != nil {
+		return nil, grantError(err)
+	}
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(authService.AccessTokenDuration().Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        subject.Scope,
+	}, nil
+}
+
+// grantError 将令牌相关的错误转换为 invalid_grant, 其余错误视为服务端错误
+func grantError(err error) error {
+	switch err {
+	case builtin.ErrTokenInvalid, builtin.ErrTokenExpired, builtin.ErrTokenRevoked,
+		builtin.ErrTokenTypeInvalid, builtin.ErrUserNotFound:
+		return ErrInvalidGrant
+	}
+	return ErrServerError
+}
+
+// Introspect 令牌内省, 无效的令牌只返回 active=false
+//
+// 客户端只能内省签发给自己的令牌, 用户登录的令牌和其他客户端的令牌同样返回 active=false, 不泄露账号信息
+func (s *OauthService) Introspect(client *model.OauthClientModel, token string) *IntrospectionResponse {
+	claims, ok := s.tokens.Introspect(token)
+	if !ok || claims.ClientID != client.ClientID {
+		return &IntrospectionResponse{Active: false}
+	}
+	response := &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.AccountId,
+		TokenType: "Bearer",
+		Sub:       claims.GID,
+		Jti:       claims.ID,
+	}
+	if claims.Subject == auth.RefreshToken {
+		response.TokenType = GrantRefreshToken
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		response.Nbf = claims.NotBefore.Unix()
+	}
+	return response
+}
+
+// Revoke 吊销客户端自己的令牌
+func (s *OauthService) Revoke(client *model.OauthClientModel, token string) error {
+	if token == "" {
+		return ErrInvalidRequest.WithDescription("token is required")
+	}
+	if err := s.tokens.RevokeToken(token, client.ClientID); err != nil {
+		return ErrServerError
+	}
+	return nil
+}
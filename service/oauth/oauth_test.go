@@ -0,0 +1,461 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"template/global/config"
+	"template/internal/builtin"
+	"template/model"
+	"template/service/auth"
+	"template/service/rbac"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	testRedirectURI = "https://app.example.com/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+type memoryClients struct {
+	clients map[string]*model.OauthClientModel
+	codes   map[string]*model.OauthCodeModel
+}
+
+func (m *memoryClients) CreateClient(client *model.OauthClientModel) error {
+	m.clients[client.ClientID] = client
+	return nil
+}
+
+func (m *memoryClients) FindClient(clientID string) (*model.OauthClientModel, error) {
+	client, ok := m.clients[clientID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return client, nil
+}
+
+func (m *memoryClients) ListClients(ownerGID string) ([]*model.OauthClientModel, error) {
+	clients := make([]*model.OauthClientModel, 0)
+	for _, client := range m.clients {
+		if ownerGID == "" || client.OwnerGID == ownerGID {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+func (m *memoryClients) DeleteClient(clientID string) error {
+	delete(m.clients, clientID)
+	return nil
+}
+
+func (m *memoryClients) SaveCode(code *model.OauthCodeModel) error {
+	m.codes[code.CodeHash] = code
+	return nil
+}
+
+func (m *memoryClients) ConsumeCode(codeHash string, at time.Time) (*model.OauthCodeModel, bool, error) {
+	code, ok := m.codes[codeHash]
+	if !ok {
+		return nil, false, gorm.ErrRecordNotFound
+	}
+	if code.UsedAt != nil {
+		return code, false, nil
+	}
+	code.UsedAt = &at
+	return code, true, nil
+}
+
+type memoryAccounts map[string]*model.AccountModel
+
+func (m memoryAccounts) FindByGID(gid string) (*model.AccountModel, error) {
+	account, ok := m[gid]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return account, nil
+}
+
+type testService struct {
+	*OauthService
+	clients *memoryClients
+	tokens  *auth.AuthService
+}
+
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+	tokens, err := auth.New(config.TokenConfig{}, "secret", nil, auth.NewMemoryTokenStore())
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+	clients := &memoryClients{
+		clients: map[string]*model.OauthClientModel{
+			"confidential": {
+				ClientID:     "confidential",
+				RedirectURIs: testRedirectURI,
+				GrantTypes:   "authorization_code refresh_token client_credentials",
+				Scopes:       "user:read user:write",
+				OwnerGID:     "alice-gid",
+			},
+			"public": {
+				ClientID:     "public",
+				RedirectURIs: testRedirectURI + " https://app.example.com/other",
+				GrantTypes:   "authorization_code",
+				Scopes:       "user:read",
+				Public:       true,
+			},
+		},
+		codes: make(map[string]*model.OauthCodeModel),
+	}
+	accounts := memoryAccounts{"alice-gid": {Model: gorm.Model{ID: 1}, GID: "alice-gid", Email: "alice@example.com"}}
+	return &testService{
+		OauthService: New(clients, accounts, tokens, config.PasswordConfig{BcryptCost: 4, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1}),
+		clients:      clients,
+		tokens:       tokens,
+	}
+}
+
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize 校验授权请求并签发授权码
+func (s *testService) authorize(t *testing.T, request AuthorizeRequest) string {
+	t.Helper()
+	request.ResponseType = "code"
+	if _, err := s.ValidateAuthorize(&request); err != nil {
+		t.Fatalf("ValidateAuthorize: %v", err)
+	}
+	code, err := s.IssueCode(&request, "alice-gid")
+	if err != nil {
+		t.Fatalf("IssueCode: %v", err)
+	}
+	return code
+}
+
+func TestRegisterClient(t *testing.T) {
+	creator := &auth.OauthClaims{GID: "alice-gid", Perms: int64(rbac.PermUserRead | rbac.PermUserWrite)}
+	admin := &auth.OauthClaims{GID: "admin-gid", Perms: int64(rbac.PermAll)}
+	tests := map[string]struct {
+		client  model.OauthClientModel
+		creator *auth.OauthClaims
+		err     error
+	}{
+		"confidential":              {model.OauthClientModel{Scopes: "user:read", GrantTypes: "authorization_code client_credentials"}, creator, nil},
+		"public":                    {model.OauthClientModel{Scopes: "user:read user:write", GrantTypes: "authorization_code", Public: true}, creator, nil},
+		"admin":                     {model.OauthClientModel{Scopes: "role:manage client:manage"}, admin, nil},
+		"wildcard scope":            {model.OauthClientModel{Scopes: "*"}, admin, builtin.ErrInvalidParams},
+		"unknown scope":             {model.OauthClientModel{Scopes: "user:read openid"}, creator, builtin.ErrInvalidParams},
+		"scope above creator":       {model.OauthClientModel{Scopes: "user:read role:manage"}, creator, builtin.ErrForbidden},
+		"public client credentials": {model.OauthClientModel{Scopes: "user:read", GrantTypes: "client_credentials", Public: true}, creator, builtin.ErrInvalidParams},
+		"unknown grant type":        {model.OauthClientModel{Scopes: "user:read", GrantTypes: "password"}, creator, builtin.ErrInvalidParams},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestService(t)
+			client := test.client
+			secret, err := s.RegisterClient(&client, test.creator)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("RegisterClient err = %v, want %v", err, test.err)
+			}
+			if err != nil {
+				if _, ok := s.clients.clients[client.ClientID]; ok {
+					t.Fatal("rejected client was saved")
+				}
+				return
+			}
+			if client.ClientID == "" || s.clients.clients[client.ClientID] == nil {
+				t.Fatal("client was not saved")
+			}
+			if client.Public {
+				if secret != "" || client.SecretHash != "" {
+					t.Fatalf("public client has a secret: %q, %q", secret, client.SecretHash)
+				}
+				return
+			}
+			if secret == "" || client.SecretHash == secret {
+				t.Fatalf("secret = %q, hash = %q, want a hashed secret", secret, client.SecretHash)
+			}
+			if _, err := s.AuthenticateClient(client.ClientID, secret); err != nil {
+				t.Fatalf("AuthenticateClient with the secret: %v", err)
+			}
+			if _, err := s.AuthenticateClient(client.ClientID, secret+"x"); !errors.Is(err, ErrInvalidClient) {
+				t.Fatalf("AuthenticateClient with a wrong secret: err = %v, want %v", err, ErrInvalidClient)
+			}
+		})
+	}
+}
+
+func TestValidateAuthorize(t *testing.T) {
+	tests := map[string]struct {
+		request  AuthorizeRequest
+		err      error
+		redirect bool
+		want     AuthorizeRequest
+	}{
+		"default redirect and scope": {
+			request:  AuthorizeRequest{ResponseType: "code", ClientID: "confidential"},
+			redirect: true,
+			want:     AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "user:read user:write"},
+		},
+		"plain by default for confidential clients": {
+			request:  AuthorizeRequest{ResponseType: "code", ClientID: "confidential", CodeChallenge: "challenge"},
+			redirect: true,
+			want:     AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "user:read user:write", CodeChallengeMethod: ChallengePlain},
+		},
+		"public S256": {
+			request:  AuthorizeRequest{ResponseType: "code", ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			redirect: true,
+			want:     AuthorizeRequest{RedirectURI: testRedirectURI, Scope: "user:read", CodeChallengeMethod: ChallengeS256},
+		},
+		"unknown client":               {request: AuthorizeRequest{ResponseType: "code", ClientID: "unknown"}, err: ErrInvalidClient},
+		"ambiguous redirect":           {request: AuthorizeRequest{ResponseType: "code", ClientID: "public"}, err: ErrInvalidRequest},
+		"unregistered redirect":        {request: AuthorizeRequest{ResponseType: "code", ClientID: "confidential", RedirectURI: "https://evil.example.com/callback"}, err: ErrInvalidRequest},
+		"redirect with trailing slash": {request: AuthorizeRequest{ResponseType: "code", ClientID: "confidential", RedirectURI: testRedirectURI + "/"}, err: ErrInvalidRequest},
+		"redirect with query":          {request: AuthorizeRequest{ResponseType: "code", ClientID: "confidential", RedirectURI: testRedirectURI + "?next=x"}, err: ErrInvalidRequest},
+		"redirect prefix":              {request: AuthorizeRequest{ResponseType: "code", ClientID: "confidential", RedirectURI: "https://app.example.com/call"}, err: ErrInvalidRequest},
+		"token response type":          {request: AuthorizeRequest{ResponseType: "token", ClientID: "confidential"}, err: ErrUnsupportedResponseType, redirect: true},
+		"scope not allowed":            {request: AuthorizeRequest{ResponseType: "code", ClientID: "confidential", Scope: "user:read role:manage"}, err: ErrInvalidScope, redirect: true},
+		"public without challenge":     {request: AuthorizeRequest{ResponseType: "code", ClientID: "public", RedirectURI: testRedirectURI}, err: ErrInvalidRequest, redirect: true},
+		"public plain": {
+			request:  AuthorizeRequest{ResponseType: "code", ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: testVerifier, CodeChallengeMethod: ChallengePlain},
+			err:      ErrInvalidRequest,
+			redirect: true,
+		},
+		"public method omitted": {
+			request:  AuthorizeRequest{ResponseType: "code", ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: testVerifier},
+			err:      ErrInvalidRequest,
+			redirect: true,
+		},
+		"unknown method": {
+			request:  AuthorizeRequest{ResponseType: "code", ClientID: "confidential", CodeChallenge: "challenge", CodeChallengeMethod: "S512"},
+			err:      ErrInvalidRequest,
+			redirect: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestService(t)
+			request := test.request
+			client, err := s.ValidateAuthorize(&request)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ValidateAuthorize err = %v, want %v", err, test.err)
+			}
+			// 客户端或回调地址无效时不返回客户端, 调用方不能重定向
+			if (client != nil) != test.redirect {
+				t.Fatalf("client = %v, want redirect = %v", client, test.redirect)
+			}
+			if err != nil {
+				return
+			}
+			if request.RedirectURI != test.want.RedirectURI || request.Scope != test.want.Scope || request.CodeChallengeMethod != test.want.CodeChallengeMethod {
+				t.Fatalf("request = %+v, want %+v", request, test.want)
+			}
+		})
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	tests := map[string]struct {
+		request     AuthorizeRequest
+		client      string
+		redirectURI string
+		verifier    string
+		err         error
+	}{
+		"confidential without PKCE": {
+			request: AuthorizeRequest{ClientID: "confidential"},
+			client:  "confidential",
+		},
+		"S256": {
+			request:     AuthorizeRequest{ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			client:      "public",
+			redirectURI: testRedirectURI,
+			verifier:    testVerifier,
+		},
+		"plain": {
+			request:  AuthorizeRequest{ClientID: "confidential", CodeChallenge: testVerifier},
+			client:   "confidential",
+			verifier: testVerifier,
+		},
+		"S256 wrong verifier": {
+			request:     AuthorizeRequest{ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			client:      "public",
+			redirectURI: testRedirectURI,
+			verifier:    testVerifier + "x",
+			err:         ErrInvalidGrant,
+		},
+		"S256 challenge as verifier": {
+			request:     AuthorizeRequest{ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			client:      "public",
+			redirectURI: testRedirectURI,
+			verifier:    s256(testVerifier),
+			err:         ErrInvalidGrant,
+		},
+		"missing verifier": {
+			request:     AuthorizeRequest{ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			client:      "public",
+			redirectURI: testRedirectURI,
+			err:         ErrInvalidGrant,
+		},
+		"redirect mismatch": {
+			request:     AuthorizeRequest{ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			client:      "public",
+			redirectURI: "https://app.example.com/other",
+			verifier:    testVerifier,
+			err:         ErrInvalidGrant,
+		},
+		"redirect omitted with several registered": {
+			request:  AuthorizeRequest{ClientID: "public", RedirectURI: testRedirectURI, CodeChallenge: s256(testVerifier), CodeChallengeMethod: ChallengeS256},
+			client:   "public",
+			verifier: testVerifier,
+			err:      ErrInvalidGrant,
+		},
+		"other client": {
+			request: AuthorizeRequest{ClientID: "confidential"},
+			client:  "public",
+			err:     ErrInvalidGrant,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestService(t)
+			code := s.authorize(t, test.request)
+			client := s.clients.clients[test.client]
+			response, err := s.ExchangeCode(client, code, test.redirectURI, test.verifier)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ExchangeCode err = %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			claims, err := s.tokens.Authenticate(response.AccessToken)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if claims.GID != "alice-gid" || claims.ClientID != test.client || claims.Scope != response.Scope {
+				t.Fatalf("claims = %+v, response = %+v", claims, response)
+			}
+			if hasRefresh := response.RefreshToken != ""; hasRefresh != client.AllowGrantType(GrantRefreshToken) {
+				t.Fatalf("refresh token issued = %v", hasRefresh)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeOnce(t *testing.T) {
+	s := newTestService(t)
+	code := s.authorize(t, AuthorizeRequest{ClientID: "confidential"})
+	client := s.clients.clients["confidential"]
+
+	if _, err := s.ExchangeCode(client, code, "", ""); err != nil {
+		t.Fatalf("first ExchangeCode: %v", err)
+	}
+	if _, err := s.ExchangeCode(client, code, "", ""); !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("reused code: err = %v, want %v", err, ErrInvalidGrant)
+	}
+	if _, err := s.ExchangeCode(client, "unknown", "", ""); !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("unknown code: err = %v, want %v", err, ErrInvalidGrant)
+	}
+
+	expired := s.authorize(t, AuthorizeRequest{ClientID: "confidential"})
+	s.clients.codes[hashCode(expired)].ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := s.ExchangeCode(client, expired, "", ""); !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("expired code: err = %v, want %v", err, ErrInvalidGrant)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	tests := map[string]struct {
+		client string
+		scope  string
+		want   string
+		err    error
+	}{
+		"default scope":     {"confidential", "", "user:read user:write", nil},
+		"narrower scope":    {"confidential", "user:read", "user:read", nil},
+		"scope not allowed": {"confidential", "user:read role:manage", "", ErrInvalidScope},
+		"wildcard scope":    {"confidential", "*", "", ErrInvalidScope},
+		"public client":     {"public", "", "", ErrUnauthorizedClient},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestService(t)
+			response, err := s.ClientCredentials(s.clients.clients[test.client], test.scope)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ClientCredentials err = %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			claims, err := s.tokens.Authenticate(response.AccessToken)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			want, _ := rbac.ParsePermissions(strings.Fields(test.want)...)
+			if response.Scope != test.want || claims.Permissions() != want || response.RefreshToken != "" {
+				t.Fatalf("response = %+v, permissions = %v, want scope %q", response, claims.Permissions(), test.want)
+			}
+		})
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	s := newTestService(t)
+	confidential := s.clients.clients["confidential"]
+	own, err := s.ClientCredentials(confidential, "user:read")
+	if err != nil {
+		t.Fatalf("ClientCredentials: %v", err)
+	}
+	other, _, err := s.tokens.ClientToken(auth.OauthClaims{ClientID: "public", Scope: "user:read"}, false)
+	if err != nil {
+		t.Fatalf("ClientToken: %v", err)
+	}
+	user, _, err := s.tokens.Token("alice@example.com", "alice-gid", 1)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	if response := s.Introspect(confidential, own.AccessToken); !response.Active || response.ClientID != "confidential" || response.Scope != "user:read" {
+		t.Fatalf("own token = %+v, want active", response)
+	}
+	for name, token := range map[string]string{"other client": other, "user": user, "invalid": "invalid"} {
+		if response := s.Introspect(confidential, token); response.Active || response.Username != "" || response.Sub != "" {
+			t.Errorf("%s token = %+v, want inactive without details", name, response)
+		}
+	}
+}
+
+func TestDeleteClient(t *testing.T) {
+	s := newTestService(t)
+	client := s.clients.clients["confidential"]
+	response, err := s.ClientCredentials(client, "")
+	if err != nil {
+		t.Fatalf("ClientCredentials: %v", err)
+	}
+
+	if err := s.DeleteClient("confidential", "bob-gid"); !errors.Is(err, builtin.ErrNotFound) {
+		t.Fatalf("delete by another owner: err = %v, want %v", err, builtin.ErrNotFound)
+	}
+	if _, err := s.tokens.Authenticate(response.AccessToken); err != nil {
+		t.Fatalf("token revoked by a rejected delete: %v", err)
+	}
+	if err := s.DeleteClient("confidential", "alice-gid"); err != nil {
+		t.Fatalf("DeleteClient: %v", err)
+	}
+	if _, ok := s.clients.clients["confidential"]; ok {
+		t.Fatal("client was not deleted")
+	}
+	if _, err := s.tokens.Authenticate(response.AccessToken); !errors.Is(err, builtin.ErrTokenRevoked) {
+		t.Fatalf("token of deleted client: err = %v, want %v", err, builtin.ErrTokenRevoked)
+	}
+	if err := s.DeleteClient("public", ""); err != nil {
+		t.Fatalf("delete without owner: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"template/common/utils"
+	"template/dao/oauth"
+	"template/global/config"
+	"template/internal/builtin"
+	"template/model"
+	"template/service/account"
+	"template/service/auth"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// stateSubject 登录状态令牌的类型, 不能作为访问令牌使用
+	stateSubject = "oidc_state"
+	// stateLifetime 用户在提供方完成登录的最长时间
+	stateLifetime = 10 * time.Minute
+)
+
+// Registry 外部登录提供方注册表
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]*Provider
+}
+
+// NewRegistry 按配置创建注册表, 缺少必要配置的提供方会被忽略并写入 logger
+func NewRegistry(configs config.OIDCConfig, logger *zap.Logger) *Registry {
+	registry := &Registry{providers: make(map[string]*Provider)}
+	for name, conf := range configs {
+		if conf.Issuer == "" || conf.ClientID == "" || conf.RedirectURL == "" {
+			logger.Warn("skip oidc provider with incomplete config", zap.String("provider", name))
+			continue
+		}
+		registry.Register(NewProvider(name, conf, nil))
+	}
+	return registry
+}
+
+// Register 注册或替换提供方
+func (r *Registry) Register(provider *Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[provider.Name] = provider
+}
+
+func (r *Registry) Get(name string) (*Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Providers 按名称排序的提供方
+func (r *Registry) Providers() []*Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	providers := make([]*Provider, 0, len(r.providers))
+	for _, provider := range r.providers {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+// loginState 跳转到提供方前保存的登录状态, 签名后放在 cookie 中, 回调时校验
+type loginState struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// IdentityStore 提供方用户与本地账号的关联
+type IdentityStore interface {
+	FindIdentity(provider, subject string) (*model.AccountIdentityModel, error)
+	SaveIdentity(identity *model.AccountIdentityModel) error
+}
+
+// AccountStore 外部登录查找和创建本地账号
+type AccountStore interface {
+	GetByGID(gid string) (*model.AccountModel, error)
+	GetByEmail(email string) (*model.AccountModel, error)
+	Create(account *model.AccountModel) error
+}
+
+var (
+	_ IdentityStore = (*oauth.OauthDao)(nil)
+	_ AccountStore  = (*account.AccountService)(nil)
+)
+
+// OIDCService 通过 New 创建
+type OIDCService struct {
+	registry   *Registry
+	identities IdentityStore
+	tokens     *auth.AuthService
+	accounts   AccountStore
+	logger     *zap.Logger
+}
+
+// New 使用 registry 中的提供方登录, 登录状态由 tokens 的密钥签名, 关联保存在 identities, 账号由 accounts 创建, 登录失败的原因写入 logger
+func New(identities IdentityStore, registry *Registry, tokens *auth.AuthService, accounts AccountStore, logger *zap.Logger) *OIDCService {
+	return &OIDCService{registry: registry, identities: identities, tokens: tokens, accounts: accounts, logger: logger}
+}
+
+// SetRegistry 替换提供方注册表
+func (s *OIDCService) SetRegistry(registry *Registry) {
+	s.registry = registry
+}
+
+// Registry 提供方注册表
+func (s *OIDCService) Registry() *Registry {
+	return s.registry
+}
+
+func randomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Begin 开始外部登录, 返回提供方授权地址和需要写入 cookie 的登录状态
+func (s *OIDCService) Begin(ctx context.Context, providerName string) (authURL, state string, err error) {
+	provider, ok := s.Registry().Get(providerName)
+	if !ok {
+		return "", "", builtin.ErrProviderNotFound
+	}
+	login := &loginState{Provider: providerName}
+	for _, field := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		if *field, err = randomString(32); err != nil {
+			return "", "", builtin.ErrInternalServer
+		}
+	}
+	now := time.Now()
+	login.RegisteredClaims = jwt.RegisteredClaims{
+		Subject:   stateSubject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(stateLifetime)),
+	}
+
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	authURL, err = provider.AuthCodeURL(ctx, login.State, login.Nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		s.logError("oidc discovery failed", providerName, err)
+		return "", "", builtin.ErrExternalLogin
+	}
+	if state, err = s.tokens.KeyRing().Sign(login); err != nil {
+		return "", "", builtin.ErrInternalServer
+	}
+	return authURL, state, nil
+}
+
+// Complete 校验回调参数和 ID Token, 返回关联的本地账号
+//
+// stateCookie 为 Begin 返回的登录状态, state 和 code 为提供方回调时携带的参数
+func (s *OIDCService) Complete(ctx context.Context, providerName, stateCookie, state, code string) (*model.AccountModel, error) {
+	provider, ok := s.Registry().Get(providerName)
+	if !ok {
+		return nil, builtin.ErrProviderNotFound
+	}
+	login := new(loginState)
+	_, err := jwt.ParseWithClaims(stateCookie, login, s.tokens.KeyRing().Keyfunc, jwt.WithSubject(stateSubject))
+	if err != nil || login.Provider != providerName || state == "" ||
+		subtle.ConstantTimeCompare([]byte(login.State), []byte(state)) != 1 {
+		return nil, builtin.ErrExternalLogin
+	}
+	if code == "" {
+		return nil, builtin.ErrExternalLogin
+	}
+
+	idToken, err := provider.Exchange(ctx, code, login.Verifier)
+	if err != nil {
+		s.logError("oidc code exchange failed", providerName, err)
+		return nil, builtin.ErrExternalLogin
+	}
+	claims, err := provider.VerifyIDToken(ctx, idToken, login.Nonce)
+	if err != nil {
+		s.logError("oidc id_token verification failed", providerName, err)
+		return nil, builtin.ErrExternalLogin
+	}
+	return s.link(provider, claims)
+}
+
+// link 按提供方用户ID查找已关联的账号, 未关联时按已验证的邮箱关联, 仍未找到时按配置自动创建
+func (s *OIDCService) link(provider *Provider, claims *IDTokenClaims) (*model.AccountModel, error) {
+	accountService := s.accounts
+	identity, err := s.identities.FindIdentity(provider.Name, claims.Subject)
+	if err == nil {
+		return accountService.GetByGID(identity.AccountGID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, builtin.ErrDBQueryFailed
+	}
+
+	// 未经提供方验证的邮箱可能属于他人, 不能用于关联
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.Verified() {
+		return nil, builtin.ErrExternalLogin
+	}
+	accountModel, err := accountService.GetByEmail(email)
+	if err != nil && !errors.Is(err, builtin.ErrUserNotFound) {
+		return nil, err
+	}
+	if err != nil || accountModel.ID == 0 {
+		if !provider.Config().AutoSignup {
+			return nil, builtin.ErrUserNotFound
+		}
+		if accountModel, err = s.signup(email); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.identities.SaveIdentity(&model.AccountIdentityModel{
+		Provider:   provider.Name,
+		Subject:    claims.Subject,
+		AccountGID: accountModel.GID,
+		Email:      email,
+	})
+	if err != nil {
+		return nil, builtin.ErrDBInsertFailed
+	}
+	return accountModel, nil
+}
+
+// signup 为外部登录的用户创建账号, 使用随机密码, 用户只能通过外部登录或重置密码登录
+func (s *OIDCService) signup(email string) (*model.AccountModel, error) {
+	password, err := randomString(32)
+	if err != nil {
+		return nil, builtin.ErrInternalServer
+	}
+	accountModel := model.NewModel(
+		model.WithAccountGID(utils.NewGID()),
+		model.WithAccountPassword(password),
+	)
+	accountModel.Email = email
+	if err := s.accounts.Create(accountModel); err != nil {
+		return nil, err
+	}
+	return accountModel, nil
+}
+
+func (s *OIDCService) logError(message, provider string, err error) {
+	s.logger.Warn(message, zap.String("provider", provider), zap.Error(err))
+}
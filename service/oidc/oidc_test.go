@@ -0,0 +1,349 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"template/global/config"
+	"template/internal/builtin"
+	"template/model"
+	"template/service/auth"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	testClientID = "client"
+	testKeyID    = "key-1"
+)
+
+// fakeProvider 提供 discovery、JWKS 和 token 端点的OIDC提供方
+type fakeProvider struct {
+	server *httptest.Server
+	// key 在 JWKS 中公开的密钥, signer 为签发 ID Token 实际使用的密钥
+	key    *rsa.PrivateKey
+	signer *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	fake := &fakeProvider{key: key, signer: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                fake.server.URL,
+			AuthorizationEndpoint: fake.server.URL + "/authorize",
+			TokenEndpoint:         fake.server.URL + "/token",
+			JWKSURI:               fake.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		json.NewEncoder(w).Encode(map[string]any{"keys": []jsonWebKey{{
+			Kty: "RSA",
+			Kid: testKeyID,
+			Use: "sig",
+			N:   encode(fake.key.N.Bytes()),
+			E:   encode(big.NewInt(int64(fake.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", fake.token)
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// authorize 模拟用户在提供方完成登录, 记录 nonce 和 PKCE challenge, 返回回调的 state 和 code
+func (fake *fakeProvider) authorize(t *testing.T, authURL string) (state, code string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected auth url %s", authURL)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.challenge = query.Get("code_challenge")
+	fake.nonce = query.Get("nonce")
+	return query.Get("state"), "code"
+}
+
+func (fake *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	clientID, _, _ := r.BasicAuth()
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if clientID != testClientID || r.PostFormValue("code") != "code" ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != fake.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   fake.server.URL,
+		"aud":   testClientID,
+		"sub":   "provider-user",
+		"exp":   now.Add(time.Minute).Unix(),
+		"iat":   now.Unix(),
+		"nonce": fake.nonce,
+	}
+	for name, value := range fake.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(fake.signer)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+}
+
+type memoryIdentities struct {
+	identities map[string]*model.AccountIdentityModel
+}
+
+func (m *memoryIdentities) FindIdentity(provider, subject string) (*model.AccountIdentityModel, error) {
+	identity, ok := m.identities[provider+"/"+subject]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return identity, nil
+}
+
+func (m *memoryIdentities) SaveIdentity(identity *model.AccountIdentityModel) error {
+	m.identities[identity.Provider+"/"+identity.Subject] = identity
+	return nil
+}
+
+type memoryAccounts struct {
+	accounts []*model.AccountModel
+}
+
+func (m *memoryAccounts) GetByGID(gid string) (*model.AccountModel, error) {
+	for _, account := range m.accounts {
+		if account.GID == gid {
+			return account, nil
+		}
+	}
+	return nil, builtin.ErrUserNotFound
+}
+
+func (m *memoryAccounts) GetByEmail(email string) (*model.AccountModel, error) {
+	for _, account := range m.accounts {
+		if account.Email == email {
+			return account, nil
+		}
+	}
+	return nil, builtin.ErrUserNotFound
+}
+
+func (m *memoryAccounts) Create(account *model.AccountModel) error {
+	account.ID = uint(len(m.accounts) + 1)
+	m.accounts = append(m.accounts, account)
+	return nil
+}
+
+type testService struct {
+	*OIDCService
+	fake       *fakeProvider
+	identities *memoryIdentities
+	accounts   *memoryAccounts
+}
+
+func newTestService(t *testing.T, autoSignup bool) *testService {
+	t.Helper()
+	fake := newFakeProvider(t)
+	tokens, err := auth.New(config.TokenConfig{}, "secret", nil, auth.NewMemoryTokenStore())
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+	registry := NewRegistry(config.OIDCConfig{
+		"fake": {
+			Issuer:       fake.server.URL,
+			ClientID:     testClientID,
+			ClientSecret: "client-secret",
+			RedirectURL:  "http://localhost/api/v1/oauth2/callback/fake",
+			AutoSignup:   autoSignup,
+		},
+	}, zap.NewNop())
+	identities := &memoryIdentities{identities: make(map[string]*model.AccountIdentityModel)}
+	accounts := &memoryAccounts{accounts: []*model.AccountModel{
+		{Model: gorm.Model{ID: 1}, GID: "alice-gid", Email: "alice@example.com"},
+	}}
+	return &testService{
+		OIDCService: New(identities, registry, tokens, accounts, zap.NewNop()),
+		fake:        fake,
+		identities:  identities,
+		accounts:    accounts,
+	}
+}
+
+// login 完成一次外部登录, 返回 Complete 的结果
+func (s *testService) login(t *testing.T) (*model.AccountModel, error) {
+	t.Helper()
+	authURL, cookie, err := s.Begin(context.Background(), "fake")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	state, code := s.fake.authorize(t, authURL)
+	return s.Complete(context.Background(), "fake", cookie, state, code)
+}
+
+func TestLinkByVerifiedEmail(t *testing.T) {
+	s := newTestService(t, false)
+	s.fake.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true}
+
+	account, err := s.login(t)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if account.GID != "alice-gid" {
+		t.Fatalf("linked account = %s, want alice-gid", account.GID)
+	}
+	identity, err := s.identities.FindIdentity("fake", "provider-user")
+	if err != nil || identity.AccountGID != "alice-gid" {
+		t.Fatalf("identity = %+v, %v", identity, err)
+	}
+
+	// 已关联后按提供方用户ID查找, 不再依赖邮箱
+	s.fake.claims = jwt.MapClaims{"email": "changed@example.com"}
+	if account, err = s.login(t); err != nil || account.GID != "alice-gid" {
+		t.Fatalf("second login = %+v, %v", account, err)
+	}
+}
+
+func TestLinkRejectsUnverifiedEmail(t *testing.T) {
+	s := newTestService(t, true)
+	s.fake.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": "false"}
+
+	if _, err := s.login(t); !errors.Is(err, builtin.ErrExternalLogin) {
+		t.Fatalf("err = %v, want %v", err, builtin.ErrExternalLogin)
+	}
+	if len(s.identities.identities) != 0 {
+		t.Fatalf("identity saved for unverified email: %v", s.identities.identities)
+	}
+}
+
+func TestLinkUnknownEmail(t *testing.T) {
+	s := newTestService(t, false)
+	s.fake.claims = jwt.MapClaims{"email": "bob@example.com", "email_verified": true}
+	if _, err := s.login(t); !errors.Is(err, builtin.ErrUserNotFound) {
+		t.Fatalf("without auto signup: err = %v, want %v", err, builtin.ErrUserNotFound)
+	}
+
+	s = newTestService(t, true)
+	s.fake.claims = jwt.MapClaims{"email": "bob@example.com", "email_verified": "true"}
+	account, err := s.login(t)
+	if err != nil {
+		t.Fatalf("auto signup: %v", err)
+	}
+	if account.Email != "bob@example.com" || account.GID == "" || len(s.accounts.accounts) != 2 {
+		t.Fatalf("created account = %+v", account)
+	}
+}
+
+func TestCompleteStateMismatch(t *testing.T) {
+	s := newTestService(t, false)
+	authURL, cookie, err := s.Begin(context.Background(), "fake")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	state, code := s.fake.authorize(t, authURL)
+	_, otherCookie, err := s.Begin(context.Background(), "fake")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	cases := map[string][3]string{
+		"wrong state":           {cookie, "forged", code},
+		"empty state":           {cookie, "", code},
+		"cookie of other login": {otherCookie, state, code},
+		"tampered cookie":       {cookie + "x", state, code},
+		"missing code":          {cookie, state, ""},
+	}
+	for name, args := range cases {
+		if _, err := s.Complete(context.Background(), "fake", args[0], args[1], args[2]); !errors.Is(err, builtin.ErrExternalLogin) {
+			t.Errorf("%s: err = %v, want %v", name, err, builtin.ErrExternalLogin)
+		}
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	cases := map[string]func(fake *fakeProvider){
+		"nonce mismatch": func(fake *fakeProvider) { fake.claims = jwt.MapClaims{"nonce": "other"} },
+		"bad signature":  func(fake *fakeProvider) { fake.signer = other },
+		"wrong audience": func(fake *fakeProvider) { fake.claims = jwt.MapClaims{"aud": "other-client"} },
+		"wrong issuer":   func(fake *fakeProvider) { fake.claims = jwt.MapClaims{"iss": "https://evil.example.com"} },
+		"expired":        func(fake *fakeProvider) { fake.claims = jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()} },
+		"azp mismatch": func(fake *fakeProvider) {
+			fake.claims = jwt.MapClaims{"aud": []string{testClientID, "other-client"}, "azp": "other-client"}
+		},
+	}
+	for name, setup := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := newTestService(t, true)
+			s.fake.claims = jwt.MapClaims{"email": "alice@example.com", "email_verified": true}
+			setup(s.fake)
+			if _, err := s.login(t); !errors.Is(err, builtin.ErrExternalLogin) {
+				t.Fatalf("err = %v, want %v", err, builtin.ErrExternalLogin)
+			}
+			if len(s.identities.identities) != 0 {
+				t.Fatalf("identity saved: %v", s.identities.identities)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenNonce(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := NewProvider("fake", config.OIDCProviderConfig{Issuer: fake.server.URL, ClientID: testClientID}, fake.server.Client())
+	challenge := sha256.Sum256([]byte("verifier"))
+	fake.challenge = base64.RawURLEncoding.EncodeToString(challenge[:])
+	fake.nonce = "expected"
+
+	idToken, err := provider.Exchange(context.Background(), "code", "verifier")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), idToken, "other"); !errors.Is(err, ErrNonceMismatch) {
+		t.Fatalf("err = %v, want %v", err, ErrNonceMismatch)
+	}
+	claims, err := provider.VerifyIDToken(context.Background(), idToken, "expected")
+	if err != nil || claims.Subject != "provider-user" {
+		t.Fatalf("VerifyIDToken = %+v, %v", claims, err)
+	}
+}
@@ -0,0 +1,337 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"template/global/config"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrDiscovery     = errors.New("oidc: discovery failed")
+	ErrUnknownKey    = errors.New("oidc: unknown signing key")
+	ErrNonceMismatch = errors.New("oidc: nonce mismatch")
+)
+
+// jwksRefreshInterval 遇到未知 kid 时重新获取 JWKS 的最小间隔, 防止被恶意令牌放大请求
+const jwksRefreshInterval = time.Minute
+
+// Discovery OpenID Provider 元数据(OpenID Connect Discovery 1.0 第3节)
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDTokenClaims ID Token 中用于登录和关联账号的字段
+type IDTokenClaims struct {
+	Email           string `json:"email"`
+	EmailVerified   any    `json:"email_verified"` // 部分提供方返回字符串 "true"
+	Name            string `json:"name"`
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Verified 邮箱是否经过提供方验证, 未返回该字段时视为未验证
+func (claims *IDTokenClaims) Verified() bool {
+	switch verified := claims.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+// Provider 外部OIDC提供方, 元数据和公钥在首次使用时获取
+type Provider struct {
+	Name   string
+	config config.OIDCProviderConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *Discovery
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+func NewProvider(name string, conf config.OIDCProviderConfig, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{Name: name, config: conf, client: client}
+}
+
+// Config 提供方配置
+func (p *Provider) Config() config.OIDCProviderConfig {
+	return p.config
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(result)
+}
+
+// Discover 获取提供方元数据, 成功后缓存
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	discovery := new(Discovery)
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", discovery); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	// 元数据中的 issuer 必须与配置一致, 防止提供方被冒充
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, discovery.Issuer, p.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", ErrDiscovery)
+	}
+	p.discovery = discovery
+	return discovery, nil
+}
+
+// AuthCodeURL 构造跳转到提供方的授权地址, 使用 PKCE S256
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := []string{"openid"}
+	for _, scope := range p.config.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	values := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange 使用授权码换取 ID Token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("oidc: decode token response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("oidc: token endpoint: %s %s", result.Error, result.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || result.IDToken == "" {
+		return "", fmt.Errorf("oidc: token endpoint returned no id_token (%s)", resp.Status)
+	}
+	return result.IDToken, nil
+}
+
+// VerifyIDToken 校验 ID Token 的签名、iss、aud、exp 和 nonce(OpenID Connect Core 1.0 第3.1.3.7节)
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDTokenClaims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := new(IDTokenClaims)
+	_, err = jwt.ParseWithClaims(raw, claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.publicKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, errors.New("oidc: azp does not match client_id")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc: id_token has no subject")
+	}
+	return claims, nil
+}
+
+// publicKey 按 kid 查找公钥, 未找到时重新获取 JWKS 以支持提供方轮换密钥
+func (p *Provider) publicKey(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	p.keysFetched = time.Now()
+	p.keys = make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			p.keys[jwk.Kid] = key
+		}
+	}
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// lookupKey kid 为空且只有一个公钥时使用该公钥
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey RFC 7517 公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeSegment(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// PublicKey 转换为 jwt 校验使用的公钥
+func (jwk jsonWebKey) PublicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeSegment(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("oidc: unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeSegment(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("oidc: unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeSegment(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("oidc: invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("oidc: unsupported key type %q", jwk.Kty)
+}
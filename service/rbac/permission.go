@@ -0,0 +1,96 @@
+package rbac
+
+import (
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Permission 权限位掩码
+type Permission int64
+
+const (
+	PermUserRead Permission = 1 << iota
+	PermUserWrite
+	PermUserDelete
+	PermAccountRead
+	PermAccountWrite
+	PermRoleManage
+	PermClientManage
+)
+
+// PermAll 拥有全部权限
+const PermAll Permission = -1
+
+type permissionDefine struct {
+	permission  Permission
+	description string
+}
+
+// permissionCodes 权限标识与权限位的对应关系, 新增权限时在此注册
+var permissionCodes = map[string]permissionDefine{
+	"user:read":     {PermUserRead, "查看用户"},
+	"user:write":    {PermUserWrite, "创建和修改用户"},
+	"user:delete":   {PermUserDelete, "删除用户"},
+	"account:read":  {PermAccountRead, "查看账号"},
+	"account:write": {PermAccountWrite, "修改账号"},
+	"role:manage":   {PermRoleManage, "管理角色和授权"},
+	"client:manage": {PermClientManage, "管理OAuth2客户端"},
+	"*":             {PermAll, "全部权限"},
+}
+
+// Has 是否拥有 required 中的全部权限
+func (p Permission) Has(required Permission) bool {
+	return p&required == required
+}
+
+// Codes 权限位掩码对应的权限标识
+func (p Permission) Codes() []string {
+	if p == PermAll {
+		return []string{"*"}
+	}
+	codes := make([]string, 0, bits.OnesCount64(uint64(p)))
+	for code, define := range permissionCodes {
+		if define.permission != PermAll && p.Has(define.permission) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ParsePermissions 将权限标识转换为权限位掩码, 返回无法识别的标识
+func ParsePermissions(codes ...string) (Permission, []string) {
+	var permission Permission
+	unknown := make([]string, 0)
+	for _, code := range codes {
+		define, ok := permissionCodes[strings.TrimSpace(code)]
+		if !ok {
+			unknown = append(unknown, code)
+			continue
+		}
+		permission |= define.permission
+	}
+	return permission, unknown
+}
+
+// MustParsePermissions 同 ParsePermissions, 存在无法识别的标识时 panic, 用于注册路由
+func MustParsePermissions(codes ...string) Permission {
+	permission, unknown := ParsePermissions(codes...)
+	if len(unknown) > 0 {
+		panic("rbac: unknown permission " + strings.Join(unknown, ","))
+	}
+	return permission
+}
+
+// HasAnyRole roles 中是否包含 required 中任意一个角色
+func HasAnyRole(roles []string, required ...string) bool {
+	for _, role := range roles {
+		for _, name := range required {
+			if role == name {
+				return true
+			}
+		}
+	}
+	return false
+}
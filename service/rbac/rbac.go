@@ -0,0 +1,164 @@
+package rbac
+
+import (
+	"errors"
+	"sort"
+	"template/dao"
+	"template/internal/builtin"
+	"template/model"
+
+	"gorm.io/gorm"
+)
+
+type IRBACService interface {
+	CreateRole(role *model.RoleModel) error
+	DeleteRole(name string) error
+	UpdateRole(role *model.RoleModel) error
+	ListRoles() ([]*model.RoleModel, error)
+	Grant(accountGID, roleName string) error
+	Revoke(accountGID, roleName string) error
+	Grants(accountGID string) ([]string, Permission, error)
+}
+
+// RBACService 通过 New 创建
+//
+// 角色和权限在签发令牌时写入令牌, 修改角色、授权或撤销授权在刷新令牌时生效,
+// 已签发的访问令牌在过期前仍按旧的权限校验
+type RBACService struct {
+	dao *dao.Dao
+}
+
+// New 使用 d 访问角色和账号
+func New(d *dao.Dao) *RBACService {
+	return &RBACService{dao: d}
+}
+
+func (s *RBACService) CreateRole(role *model.RoleModel) error {
+	existingRole, err := s.dao.Role.FindByName(role.Name)
+	if err == nil && existingRole != nil {
+		return builtin.ErrConflict
+	}
+	if err = s.dao.Role.Create(role); err != nil {
+		return builtin.ErrDBInsertFailed
+	}
+	return nil
+}
+
+func (s *RBACService) DeleteRole(name string) error {
+	if _, err := s.findRole(name); err != nil {
+		return err
+	}
+	if err := s.dao.Role.Delete(name); err != nil {
+		return builtin.ErrDBDeleteFailed
+	}
+	return nil
+}
+
+func (s *RBACService) UpdateRole(role *model.RoleModel) error {
+	if _, err := s.findRole(role.Name); err != nil {
+		return err
+	}
+	if err := s.dao.Role.Update(role); err != nil {
+		return builtin.ErrDBUpdateFailed
+	}
+	return nil
+}
+
+func (s *RBACService) ListRoles() ([]*model.RoleModel, error) {
+	roles, err := s.dao.Role.List()
+	if err != nil {
+		return nil, builtin.ErrDBQueryFailed
+	}
+	return roles, nil
+}
+
+// Grant 为账号授予角色
+func (s *RBACService) Grant(accountGID, roleName string) error {
+	if _, err := s.dao.Account.FindByGID(accountGID); err != nil {
+		return builtin.ErrUserNotFound
+	}
+	if _, err := s.findRole(roleName); err != nil {
+		return err
+	}
+	if err := s.dao.Role.Grant(accountGID, roleName); err != nil {
+		return builtin.ErrDBInsertFailed
+	}
+	return nil
+}
+
+// Revoke 撤销账号的角色
+func (s *RBACService) Revoke(accountGID, roleName string) error {
+	if err := s.dao.Role.Revoke(accountGID, roleName); err != nil {
+		return builtin.ErrDBDeleteFailed
+	}
+	return nil
+}
+
+// Grants 汇总账号的角色和权限:
+// account.Role 与 account_role 表中的角色合并, 权限为各角色权限与 account.Permission 的并集
+func (s *RBACService) Grants(accountGID string) ([]string, Permission, error) {
+	account, err := s.dao.Account.FindByGID(accountGID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, builtin.ErrUserNotFound
+		}
+		return nil, 0, builtin.ErrDBQueryFailed
+	}
+
+	names, err := s.dao.Role.RolesOf(accountGID)
+	if err != nil {
+		return nil, 0, builtin.ErrDBQueryFailed
+	}
+	if account.Role != "" {
+		names = append(names, account.Role)
+	}
+	names = uniqueStrings(names)
+
+	roles, err := s.dao.Role.FindByNames(names)
+	if err != nil {
+		return nil, 0, builtin.ErrDBQueryFailed
+	}
+	permission := Permission(account.Permission)
+	for _, role := range roles {
+		permission |= Permission(role.Permission)
+	}
+	return names, permission, nil
+}
+
+// SyncPermissions 将代码中注册的权限定义写入 permission 表
+func (s *RBACService) SyncPermissions() error {
+	permissions := make([]*model.PermissionModel, 0, len(permissionCodes))
+	for code, define := range permissionCodes {
+		permissions = append(permissions, &model.PermissionModel{
+			Code:        code,
+			Bit:         int64(define.permission),
+			Description: define.description,
+		})
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].Code < permissions[j].Code })
+	return s.dao.Role.SavePermissions(permissions)
+}
+
+func (s *RBACService) findRole(name string) (*model.RoleModel, error) {
+	role, err := s.dao.Role.FindByName(name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, builtin.ErrRoleNotFound
+		}
+		return nil, builtin.ErrDBQueryFailed
+	}
+	return role, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
@@ -1,15 +1,41 @@
 package service
 
 import (
+	"template/dao"
+	"template/global/config"
 	"template/service/account"
 	"template/service/auth"
+	"template/service/oauth"
+	"template/service/oidc"
+	"template/service/rbac"
 	"template/service/user"
+
+	"go.uber.org/zap"
 )
 
 type Service struct {
-	User user.UserService
-	Auth auth.AuthService
-	Account account.AccountService
+	User    *user.UserService
+	Auth    *auth.AuthService
+	Account *account.AccountService
+	RBAC    *rbac.RBACService
+	Oauth   *oauth.OauthService
+	OIDC    *oidc.OIDCService
 }
 
-var ServiceBoot = new(Service)
\ No newline at end of file
+// New 按依赖顺序创建服务, 所有服务使用同一个 d, 运行中的警告写入 logger
+func New(d *dao.Dao, conf *config.Configure, logger *zap.Logger) (*Service, error) {
+	accounts := account.New(d, conf.System.Password)
+	grants := rbac.New(d)
+	tokens, err := auth.New(conf.System.Token, conf.System.User.Sign, grants, &d.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		User:    user.New(d, accounts),
+		Auth:    tokens,
+		Account: accounts,
+		RBAC:    grants,
+		Oauth:   oauth.New(&d.Oauth, &d.Account, tokens, conf.System.Password),
+		OIDC:    oidc.New(&d.Oauth, oidc.NewRegistry(conf.System.OIDC, logger), tokens, accounts, logger),
+	}, nil
+}
@@ -1,45 +1,70 @@
 package user
 
 import (
+	"context"
+	"errors"
+	"template/common/query"
+	"template/common/utils"
 	"template/dao"
-	"template/dto"
+	"template/dao/repository"
 	"template/internal/builtin"
 	"template/model"
+	"template/service/account"
+
+	"gorm.io/gorm"
 )
 
 type IUserService interface {
 	Create(user *model.UserModel) error
-	Delete(gid string) error
+	Delete(ctx context.Context, gid string) error
 	Update(user *model.UserModel) error
 	GetByGID(gid string) (*model.UserModel, error)
-	List(page, size int) ([]*model.UserModel, error)
+	List(q *query.Query) ([]*model.UserModel, int64, error)
+	Seek(q *query.Query) ([]*model.UserModel, query.Cursors, error)
+	Register(ctx context.Context, user *model.UserModel, account *model.AccountModel) error
+	Patch(gid string, version *int64, fields map[string]any) error
 }
 
+// UserService 通过 New 创建
 type UserService struct {
+	dao      *dao.Dao
+	accounts *account.AccountService
+}
+
+// New 使用 d 访问数据, 注册时由 accounts 计算密码哈希
+func New(d *dao.Dao, accounts *account.AccountService) *UserService {
+	return &UserService{dao: d, accounts: accounts}
 }
-var userDao = dao.APIDao.User
 
 func (s *UserService) Create(user *model.UserModel) error {
 	// 检查用户名是否存在
-	existingUser, err := userDao.FindByGID(user.GID)
+	existingUser, err := s.dao.User.FindByGID(user.GID)
 	if err == nil && existingUser != nil {
 		return builtin.ErrUserNameExists
 	}
 
-	if err = userDao.Create(user); err != nil {
+	if err = s.dao.User.Create(user); err != nil {
 		return builtin.ErrInternalServer
 	}
 	return nil
 }
 
-func (s *UserService) Delete(gid string) error {
+// Delete 在同一个事务中软删除用户和对应的账号, 删除后无法再登录
+func (s *UserService) Delete(ctx context.Context, gid string) error {
 	// 检查用户是否存在
-	existingUser, err := userDao.FindByGID(gid)
+	existingUser, err := s.dao.User.FindByGID(gid)
 	if err != nil || existingUser == nil {
 		return builtin.ErrUserNotFound
 	}
 
-	if err = userDao.Delete(gid); err != nil {
+	err = s.dao.WithTx(ctx, func(ctx context.Context) error {
+		tx := s.dao.WithContext(ctx)
+		if err := tx.User.Delete(gid); err != nil {
+			return err
+		}
+		return tx.Account.Delete(gid)
+	})
+	if err != nil {
 		return builtin.ErrDBDeleteFailed
 	}
 	return nil
@@ -47,40 +72,103 @@ func (s *UserService) Delete(gid string) error {
 
 func (s *UserService) Update(user *model.UserModel) error {
 	// 检查用户是否存在
-	existingUser, err := userDao.FindByGID(user.GID)
+	existingUser, err := s.dao.User.FindByGID(user.GID)
 	if err != nil || existingUser == nil {
 		return builtin.ErrUserNotFound
 	}
 
-	if err = userDao.Update(user); err != nil {
+	if err = s.dao.User.Update(user); err != nil {
 		return builtin.ErrDBUpdateFailed
 	}
 	return nil
 }
 
 func (s *UserService) GetByGID(gid string) (*model.UserModel, error) {
-	user, err := userDao.FindByGID(gid)
+	user, err := s.dao.User.FindByGID(gid)
 	if err != nil {
 		return nil, builtin.ErrUserNotFound
 	}
 	return user, nil
 }
 
-func (s *UserService) List(pag dto.Pagination) ([]*model.UserModel, error) {
+// List 按 q 过滤、排序和分页查询用户, 同时返回符合条件的用户总数
+func (s *UserService) List(q *query.Query) ([]*model.UserModel, int64, error) {
+	users, total, err := s.dao.User.Query(q)
+	if err != nil {
+		return nil, 0, builtin.ErrDBQueryFailed
+	}
+	return users, total, nil
+}
 
-	users, err := userDao.List(pag.Page, pag.Size)
+// Seek 按 q 游标分页查询用户, 游标无效时返回 ErrInvalidParams
+func (s *UserService) Seek(q *query.Query) ([]*model.UserModel, query.Cursors, error) {
+	users, cursors, err := s.dao.User.Seek(q)
+	if errors.Is(err, query.ErrInvalidQuery) {
+		return nil, cursors, builtin.ErrInvalidParams
+	}
 	if err != nil {
-		return nil, builtin.ErrInternalServer
+		return nil, cursors, builtin.ErrDBQueryFailed
 	}
-	return users, nil
+	return users, cursors, nil
+}
+
+// Register 在同一个事务中创建账号和用户, 两者使用同一个 gid
+func (s *UserService) Register(ctx context.Context, user *model.UserModel, accountModel *model.AccountModel) error {
+	_, err := s.dao.Account.Search(map[string]any{"email": accountModel.Email})
+	if err == nil {
+		return builtin.ErrUserNameExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return builtin.ErrDBQueryFailed
+	}
+
+	if accountModel.Password, err = s.accounts.HashPassword(accountModel.Password); err != nil {
+		return builtin.ErrInternalServer
+	}
+	gid := utils.NewGID()
+	user.GID, accountModel.GID = gid, gid
+	err = s.dao.WithTx(ctx, func(ctx context.Context) error {
+		tx := s.dao.WithContext(ctx)
+		if err := tx.Account.Create(accountModel); err != nil {
+			return err
+		}
+		return tx.User.Create(user)
+	})
+	if err != nil {
+		return builtin.ErrDBInsertFailed
+	}
+	return nil
+}
+
+// Patch 只更新 fields 中的字段, version 不为空时使用乐观锁, 版本号不一致返回 ErrConflict
+func (s *UserService) Patch(gid string, version *int64, fields map[string]any) error {
+	if _, err := s.dao.User.FindByGID(gid); err != nil {
+		return builtin.ErrUserNotFound
+	}
+	if version == nil {
+		fields["version"] = gorm.Expr("version + 1")
+		if err := s.dao.User.UpdateFields(gid, fields); err != nil {
+			return builtin.ErrDBUpdateFailed
+		}
+		return nil
+	}
+	err := s.dao.User.UpdateFieldsVersioned(gid, *version, fields)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return builtin.ErrConflict
+	}
+	if err != nil {
+		return builtin.ErrDBUpdateFailed
+	}
+	return nil
 }
 
 func (s *UserService) FindUsersByEmail(email string) (*model.UserModel, error) {
-	users,err := userDao.Search(map[string]string{
-		"email": email,
-	},1,1)
+	users, err := s.dao.User.Find("email = ?", email)
 	if err != nil {
-		return nil,builtin.ErrDBQueryFailed
+		return nil, builtin.ErrDBQueryFailed
+	}
+	if len(users) == 0 {
+		return nil, builtin.ErrUserNotFound
 	}
 	return users[0], nil
 }